@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"time"
@@ -28,8 +29,30 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to read file:%s, err:%v\n", *configPath, err)
 	}
+	// 用户服务注册了 /user/* 等通配符路由，MapBasedHandler 只支持静态路径
+	if conf.Servers[0].Router == server.HandlerMap {
+		log.Fatalf("router %q only supports static paths, use %q or %q\n", server.HandlerMap, server.HandlerTree, server.HandlerRadix)
+	}
 	g := server.NewGracefulShutdown()
-	svr := server.NewServer(g.RejectRequestMiddleware(), middleware.Metric())
+	h, err := server.NewHandler(conf.Servers[0].Router,
+		g.RejectRequestMiddleware(),
+		middleware.Metric(),
+		middleware.Body(ctx.BodyOptions{
+			MaxBytes:              conf.Servers[0].MaxBodySize,
+			DisallowUnknownFields: conf.Servers[0].DisallowUnknownFields,
+		}),
+	)
+	if err != nil {
+		log.Fatalf("failed to create handler, err:%v\n", err)
+	}
+	svr := server.NewServerWithHandler(h)
+	svr.SetPathOptions(server.PathOptions{
+		CleanPath:             conf.Servers[0].CleanPath,
+		RedirectCleanPath:     conf.Servers[0].RedirectCleanPath,
+		RedirectTrailingSlash: conf.Servers[0].RedirectTrailingSlash,
+	})
+	svr.NotFound(service.NotFound)
+	svr.MethodNotAllowed(service.MethodNotAllowed)
 
 	// 启动rabbitmq
 	mqCliConf, err := conf.GetCliConfigByName("rabbitmq")
@@ -60,6 +83,9 @@ func main() {
 	service.RegisterUserService(svr, userSvc)
 	service.RegisterMQService(svr, mqSvc)
 	service.RegisterKafkaService(svr, kafSvr)
+	if conf.Servers[0].Debug {
+		svr.Route(http.MethodGet, server.DebugRoutesPath, server.RoutesHandler(svr))
+	}
 
 	// 启用优雅关闭
 	go WaitForShutdown(g.WaitServerShutdown(svr),
@@ -3,10 +3,16 @@ module myserver
 go 1.17
 
 require (
-	github.com/google/uuid v1.3.0 // indirect
+	github.com/google/uuid v1.3.0
+	github.com/rabbitmq/amqp091-go v1.5.0
+	github.com/segmentio/kafka-go v0.4.38
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	google.golang.org/protobuf v1.28.1
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
 	github.com/klauspost/compress v1.15.9 // indirect
 	github.com/pierrec/lz4/v4 v4.1.15 // indirect
-	github.com/rabbitmq/amqp091-go v1.5.0 // indirect
-	github.com/segmentio/kafka-go v0.4.38 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 )
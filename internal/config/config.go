@@ -15,6 +15,19 @@ type ServerConfig struct {
 	Name     string `json:"name" yaml:"name"`
 	Listen   string `json:"listen" yaml:"listen"`
 	Protocol string `json:"http" yaml:"http"`
+	// 路由的实现，可选 tree、radix，为空时使用tree
+	// 服务中有通配符路由，不能用只支持静态路径的 map
+	Router string `json:"router" yaml:"router"`
+	// 开启后注册 /debug/routes 等调试路由
+	Debug bool `json:"debug" yaml:"debug"`
+	// 路径规范化，见 server.PathOptions
+	CleanPath             bool `json:"clean_path" yaml:"clean_path"`
+	RedirectCleanPath     bool `json:"redirect_clean_path" yaml:"redirect_clean_path"`
+	RedirectTrailingSlash bool `json:"redirect_trailing_slash" yaml:"redirect_trailing_slash"`
+	// 请求body的最大字节数，为0时使用 ctx.DefaultMaxBodySize
+	MaxBodySize int64 `json:"max_body_size" yaml:"max_body_size"`
+	// 解析JSON时不允许未知字段
+	DisallowUnknownFields bool `json:"disallow_unknown_fields" yaml:"disallow_unknown_fields"`
 }
 
 type LogConfig struct {
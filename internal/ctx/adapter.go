@@ -0,0 +1,44 @@
+package ctx
+
+import "net/http"
+
+// WrapHandler 把 http.Handler 转成 HandleFunc，比如 pprof、http.FileServer
+func WrapHandler(h http.Handler) HandleFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.W, c.R)
+	}
+}
+
+// WrapHandlerFunc 把 http.HandlerFunc 转成 HandleFunc
+func WrapHandlerFunc(f http.HandlerFunc) HandleFunc {
+	return WrapHandler(f)
+}
+
+// WrapMiddleware 把标准库风格的中间件 func(http.Handler) http.Handler 转成 HandleFunc
+// 中间件调用next时继续执行后续的handler，中间件替换过的 w、r 会传给后续的handler
+// 没有调用next时(比如鉴权失败)后续的handler不再执行
+func WrapMiddleware(mw func(http.Handler) http.Handler) HandleFunc {
+	return func(c *Context) {
+		called := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			c.W = w
+			c.R = r
+			c.Next()
+		})
+		mw(next).ServeHTTP(c.W, c.R)
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+// ToHandler 把一组 HandleFunc 转成 http.Handler，可以在标准库的 ServeMux 等地方使用
+func ToHandler(hs ...HandleFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := NewContext(w, r)
+		c.Hs = hs
+		defer c.Finish()
+		c.Next()
+	})
+}
@@ -0,0 +1,84 @@
+package ctx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrapMiddleware(t *testing.T) {
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	tests := []struct {
+		name       string
+		authHeader string
+		wantCode   int
+		wantCalled bool
+	}{
+		{name: "short circuit", wantCode: http.StatusUnauthorized},
+		{name: "call next", authHeader: "token", wantCode: http.StatusOK, wantCalled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			after := false
+			h := ToHandler(func(c *Context) {
+				c.Next()
+				after = true
+			}, WrapMiddleware(auth), func(c *Context) {
+				called = true
+				c.W.WriteHeader(http.StatusOK)
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authHeader != "" {
+				r.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+			if !after {
+				t.Errorf("outer middleware did not resume after c.Next()")
+			}
+		})
+	}
+}
+
+func TestToHandlerFinishOnPanic(t *testing.T) {
+	var s *SSEStream
+	h := ToHandler(func(c *Context) {
+		var err error
+		if s, err = c.SSE(); err != nil {
+			t.Fatal(err)
+		}
+		panic("boom")
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("handler did not panic")
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	select {
+	case <-s.Done():
+	default:
+		t.Errorf("SSE stream not closed after panic")
+	}
+}
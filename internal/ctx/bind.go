@@ -0,0 +1,202 @@
+package ctx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ErrUnsupportedMediaType Bind 不支持请求的 Content-Type
+var ErrUnsupportedMediaType = errors.New("ctx: unsupported media type")
+
+// defaultMultipartMemory multipart表单默认在内存中保存的最大字节数，和 net/http 的默认值一样
+const defaultMultipartMemory = 32 << 20
+
+// Bind 把请求填充到结构体v，然后按 validate 标签校验，v必须是结构体指针
+// 先按 Content-Type 解析body，JSON、MessagePack使用json标签，表单使用form标签
+// 之后依次使用 path、query、header 标签从路径参数、query、请求头取值，有值时覆盖前面的结果
+//
+//	type ListReq struct {
+//		ID    int    `path:"id"`
+//		Page  int    `query:"page" validate:"min=1"`
+//		Size  *int   `query:"size" validate:"min=1,max=100"`
+//		Token string `header:"X-Token" validate:"required"`
+//	}
+//
+// 数字的0也会按 min、max 等规则校验，上面没有传page时返回错误，可选的数字字段用指针，比如size
+//
+// 类型转换和校验失败时返回 FieldErrors，body格式错误时返回解析的错误
+func (c *Context) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("ctx: Bind requires a non-nil struct pointer, got %T", v))
+	}
+
+	if err := c.bindBody(v); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) && te.Field != "" {
+			return FieldErrors{{Field: te.Field, Rule: "type", Reason: "must be " + te.Type.String()}}
+		}
+		if name, ok := unknownField(err); ok {
+			return FieldErrors{{Field: name, Rule: "unknown", Reason: "unknown field"}}
+		}
+		return err
+	}
+
+	var errs FieldErrors
+	c.bindValues(rv.Elem(), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return Validate(v)
+}
+
+// bindBody 没有body时直接跳过，按 Content-Type 选择 RegisterDecoder 注册的解析函数
+// 没有 Content-Type 时按JSON解析
+func (c *Context) bindBody(v interface{}) error {
+	if c.R.Body == nil || c.R.Body == http.NoBody || c.R.ContentLength == 0 {
+		return nil
+	}
+
+	mt := MIMEJSON
+	if ct := c.R.Header.Get("Content-Type"); ct != "" {
+		var err error
+		if mt, _, err = mime.ParseMediaType(ct); err != nil {
+			return ErrUnsupportedMediaType
+		}
+	}
+	d, ok := decoderFor(mt)
+	if !ok {
+		return ErrUnsupportedMediaType
+	}
+	return d(c, v)
+}
+
+func (c *Context) bindValues(v reflect.Value, errs *FieldErrors) {
+	for _, s := range specsOf(v.Type()) {
+		fv := v.Field(s.index)
+		if s.form != "" && c.bindFile(fv, s.form) {
+			continue
+		}
+		if s.form != "" {
+			c.bindField(fv, s.form, c.R.PostForm[s.form], errs)
+		}
+		if s.path != "" {
+			if val, ok := c.Params.Get(s.path); ok {
+				c.bindField(fv, s.path, []string{val}, errs)
+			}
+		}
+		if s.query != "" {
+			c.bindField(fv, s.query, c.queryValues()[s.query], errs)
+		}
+		if s.header != "" {
+			c.bindField(fv, s.header, c.R.Header.Values(s.header), errs)
+		}
+	}
+}
+
+var (
+	formFileType  = reflect.TypeOf((*FormFile)(nil))
+	formFilesType = reflect.TypeOf([]*FormFile(nil))
+)
+
+// bindFile 字段类型是 *FormFile 或者 []*FormFile 时从上传的文件中取值，返回是否是文件字段
+func (c *Context) bindFile(fv reflect.Value, name string) bool {
+	if fv.Type() != formFileType && fv.Type() != formFilesType {
+		return false
+	}
+	if c.form == nil || len(c.form.File[name]) == 0 {
+		return true
+	}
+	files := c.form.File[name]
+	if fv.Type() == formFileType {
+		fv.Set(reflect.ValueOf(files[0]))
+	} else {
+		fv.Set(reflect.ValueOf(files))
+	}
+	return true
+}
+
+func (c *Context) bindField(fv reflect.Value, name string, vals []string, errs *FieldErrors) {
+	if len(vals) == 0 {
+		return
+	}
+	if err := setField(fv, vals); err != nil {
+		*errs = append(*errs, &FieldError{Field: name, Rule: "type", Reason: err.Error()})
+	}
+}
+
+// setField 支持基础类型、time.Duration、它们的指针以及slice，slice使用所有的值，其余只使用第一个
+func setField(fv reflect.Value, vals []string) error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		nv := reflect.New(fv.Type().Elem())
+		if err := setField(nv.Elem(), vals); err != nil {
+			return err
+		}
+		fv.Set(nv)
+		return nil
+	case reflect.Slice:
+		sv := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setScalar(sv.Index(i), s); err != nil {
+				return err
+			}
+		}
+		fv.Set(sv)
+		return nil
+	}
+	return setScalar(fv, vals[0])
+}
+
+// setScalar 字段类型不支持属于代码问题，直接panic
+func setScalar(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return invalidValue(v)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return invalidValue(v)
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return invalidValue(v)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return invalidValue(v)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return invalidValue(v)
+		}
+		v.SetFloat(f)
+	default:
+		panic(fmt.Sprintf("ctx: unsupported bind field type %s", v.Type()))
+	}
+	return nil
+}
+
+func invalidValue(v reflect.Value) error {
+	return fmt.Errorf("must be a valid %s", v.Type())
+}
@@ -0,0 +1,136 @@
+package ctx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize 没有通过 SetBodyOptions 设置时，读取body的最大字节数
+var DefaultMaxBodySize int64 = 32 << 20
+
+// ErrBodyTooLarge 请求body超过限制，读取body的方法返回的错误可以用 errors.Is 判断
+var ErrBodyTooLarge = errors.New("ctx: request body too large")
+
+// BodyOptions 读取请求body的配置
+type BodyOptions struct {
+	// MaxBytes body的最大字节数，<=0 时使用 DefaultMaxBodySize
+	MaxBytes int64
+	// DisallowUnknownFields 解析JSON时遇到结构体中没有的字段直接报错
+	DisallowUnknownFields bool
+	// Multipart 解析multipart表单以及上传文件的配置
+	Multipart MultipartOptions
+}
+
+// SetBodyOptions 设置读取body的配置，可以多次设置，后设置的生效
+// 一般通过中间件设置，全局中间件设置整个server的默认值，路由上的中间件再按需覆盖
+func (c *Context) SetBodyOptions(opts BodyOptions) {
+	c.bodyOpts = opts
+	c.Body()
+	c.body.limit = c.maxBodySize()
+}
+
+// BodyOptions 返回当前读取body的配置
+func (c *Context) BodyOptions() BodyOptions {
+	return c.bodyOpts
+}
+
+func (c *Context) maxBodySize() int64 {
+	if c.bodyOpts.MaxBytes > 0 {
+		return c.bodyOpts.MaxBytes
+	}
+	return DefaultMaxBodySize
+}
+
+// Body 返回限制了大小的body，同时替换 c.R.Body，超过限制时读取返回 ErrBodyTooLarge
+func (c *Context) Body() io.ReadCloser {
+	if c.R.Body == nil {
+		c.R.Body = http.NoBody
+	}
+	if c.body == nil || c.R.Body != io.ReadCloser(c.body) {
+		c.body = &limitedBody{
+			rc:            c.R.Body,
+			limit:         c.maxBodySize(),
+			contentLength: c.R.ContentLength,
+		}
+		c.R.Body = c.body
+	}
+	return c.body
+}
+
+// decodeJson 流式解析JSON，body中只能有一个JSON值
+func (c *Context) decodeJson(data interface{}) error {
+	dec := json.NewDecoder(c.Body())
+	if c.bodyOpts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(data); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return errors.New("ctx: unexpected data after JSON body")
+	}
+	return nil
+}
+
+// unknownField 从 DisallowUnknownFields 的错误中取出字段名
+func unknownField(err error) (string, bool) {
+	const prefix = `json: unknown field "`
+	msg := err.Error()
+	if !strings.HasPrefix(msg, prefix) {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(msg, prefix), `"`), true
+}
+
+// limitedBody 限制了读取大小的body，Content-Length 已经超过限制时不读取直接报错
+type limitedBody struct {
+	rc            io.ReadCloser
+	read          int64
+	limit         int64
+	contentLength int64
+	err           error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.contentLength > b.limit {
+		return 0, b.tooLarge()
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// 多读一个字节，用来判断是否超过了限制
+	remaining := b.limit - b.read
+	if remaining < 0 {
+		return 0, b.tooLarge()
+	}
+	if int64(len(p)) > remaining+1 {
+		p = p[:remaining+1]
+	}
+	n, err := b.rc.Read(p)
+	if int64(n) > remaining {
+		b.read += remaining
+		return int(remaining), b.tooLarge()
+	}
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
+
+func (b *limitedBody) tooLarge() error {
+	b.err = fmt.Errorf("%w, limit %d bytes", ErrBodyTooLarge, b.limit)
+	return b.err
+}
@@ -3,53 +3,125 @@ package ctx
 import (
 	"context"
 	"encoding/json"
-	"io"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 )
 
 type HandleFunc func(c *Context)
 
 type Hook func(ctx context.Context) error
 
+// Param 路由中 :name 或者 * 匹配到的路径参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+type Params []Param
+
+// Get 按名字取路径参数，找不到返回false
+func (ps Params) Get(key string) (string, bool) {
+	for i := range ps {
+		if ps[i].Key == key {
+			return ps[i].Value, true
+		}
+	}
+	return "", false
+}
+
 type Context struct {
-	W   http.ResponseWriter
-	R   *http.Request
-	Hs  []HandleFunc
-	idx int
+	W      http.ResponseWriter
+	R      *http.Request
+	Hs     []HandleFunc
+	Params Params
+	idx    int
+
+	// 解析过的query，第一次访问时解析
+	query url.Values
+	// Query*、Param* 转换失败的参数
+	paramErrs []*ParamError
+
+	bodyOpts BodyOptions
+	body     *limitedBody
+	// 解析过的multipart表单
+	form    *MultipartForm
+	formErr error
+
+	// 包装后的W，中间件替换了W的话仍然指向最初的包装
+	rw *responseWriter
+
+	// Set/Get 保存的值
+	mu   sync.RWMutex
+	keys map[string]interface{}
+
+	// 服务开始关闭时被关闭，见 Draining
+	draining <-chan struct{}
+	sse      *SSEStream
+	ws       *WSConn
 }
 
+var _ context.Context = &Context{}
+
+// NewContext 把w包装成 ResponseWriter，路由创建的Context都会记录状态码和body大小
 func NewContext(w http.ResponseWriter, r *http.Request) *Context {
+	rw, ok := w.(*responseWriter)
+	if !ok {
+		rw = newResponseWriter(w)
+	}
 	return &Context{
-		W:   w,
+		W:   rw,
 		R:   r,
+		rw:  rw,
 		idx: -1,
 	}
 }
 
-func (c *Context) ReadJson(data interface{}) error {
-	buf, err := io.ReadAll(c.R.Body)
-	if err != nil {
-		return err
+// Finish 请求处理完之后由路由调用(handler panic时也会调用)，删除上传产生的临时文件，结束SSE、WebSocket
+func (c *Context) Finish() {
+	if c.form != nil {
+		c.form.RemoveAll()
 	}
-
-	if err := json.Unmarshal(buf, data); err != nil {
-		return err
+	if c.sse != nil {
+		c.sse.Close()
+	}
+	if c.ws != nil {
+		c.ws.Close(CloseNormalClosure, "")
 	}
+}
 
-	return nil
+// Response 返回记录了状态码、body大小的 ResponseWriter
+func (c *Context) Response() ResponseWriter {
+	return c.rw
 }
 
+// ReadJson 流式解析body中的JSON，body大小以及是否允许未知字段见 SetBodyOptions
+func (c *Context) ReadJson(data interface{}) error {
+	return c.decodeJson(data)
+}
+
+// WriteJson 以JSON格式写回data，没有设置 Content-Type 时设置为JSON，按 Accept 选择格式见 Render
 func (c *Context) WriteJson(code int, data interface{}) error {
 	buf, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
+	if c.W.Header().Get("Content-Type") == "" {
+		c.W.Header().Set("Content-Type", jsonRenderer{}.ContentType())
+	}
 	c.W.WriteHeader(code)
 	c.W.Write(buf)
 	return nil
 }
 
+// Param 返回路径参数，比如 /user/:id 中的 id，通配符 * 匹配到的内容用 "*" 获取
+func (c *Context) Param(key string) string {
+	v, _ := c.Params.Get(key)
+	return v
+}
+
 // 参考gin
 func (c *Context) Next() {
 	c.idx++
@@ -63,3 +135,47 @@ func (c *Context) Abort() {
 	c.Hs = nil
 	c.idx = -1
 }
+
+// Deadline 实现 context.Context，和 c.R.Context() 一致
+func (c *Context) Deadline() (time.Time, bool) {
+	return c.requestContext().Deadline()
+}
+
+// Done 客户端断开连接或者server关闭时会被关闭，可以直接把c传给下游的调用
+//
+//	s.mq.Push(c, exchange, routingKey, body)
+func (c *Context) Done() <-chan struct{} {
+	return c.requestContext().Done()
+}
+
+func (c *Context) Err() error {
+	return c.requestContext().Err()
+}
+
+// Value key为string时先查找Set保存的值，其余的交给 c.R.Context()
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if v, ok := c.Get(k); ok {
+			return v
+		}
+	}
+	return c.requestContext().Value(key)
+}
+
+// Draining 返回服务开始关闭时会被关闭的channel，SSE、WebSocket等长连接据此主动结束
+// 没有设置时返回nil，永远不会就绪
+func (c *Context) Draining() <-chan struct{} {
+	return c.draining
+}
+
+// SetDraining 一般由 GracefulShutdown 的中间件设置
+func (c *Context) SetDraining(ch <-chan struct{}) {
+	c.draining = ch
+}
+
+func (c *Context) requestContext() context.Context {
+	if c.R == nil {
+		return context.Background()
+	}
+	return c.R.Context()
+}
@@ -0,0 +1,88 @@
+package ctx
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CookieOptions 设置cookie的配置，零值是比较安全的默认值：
+// Path为 /，HttpOnly，SameSite=Lax，https请求(包括代理转发的)自动加上Secure
+type CookieOptions struct {
+	Path   string
+	Domain string
+	// MaxAge 为0时是会话cookie，浏览器关闭后失效
+	MaxAge time.Duration
+	// SameSite 为0时是Lax，None时强制Secure
+	SameSite http.SameSite
+	// Secure 为false时只有https请求才加上Secure
+	Secure bool
+	// AllowScript 允许js读取，即不设置HttpOnly
+	AllowScript bool
+}
+
+// SetCookie 设置cookie，value会做URL编码，读取时用 Cookie 解码
+//
+//	c.SetCookie("lang", "zh-CN", ctx.CookieOptions{MaxAge: 30 * 24 * time.Hour})
+func (c *Context) SetCookie(name, value string, opts CookieOptions) {
+	ck := c.newCookie(name, url.QueryEscape(value), opts)
+	if opts.MaxAge > 0 {
+		ck.MaxAge = int(opts.MaxAge / time.Second)
+		ck.Expires = time.Now().Add(opts.MaxAge)
+	}
+	http.SetCookie(c.W, ck)
+}
+
+// Cookie 返回解码后的cookie值，不存在时返回 http.ErrNoCookie
+func (c *Context) Cookie(name string) (string, error) {
+	ck, err := c.R.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(ck.Value)
+}
+
+// DeleteCookie 让浏览器删除cookie，Path、Domain 需要和设置时一致
+func (c *Context) DeleteCookie(name string, opts CookieOptions) {
+	ck := c.newCookie(name, "", opts)
+	ck.MaxAge = -1
+	ck.Expires = time.Unix(0, 0)
+	http.SetCookie(c.W, ck)
+}
+
+func (c *Context) newCookie(name, value string, opts CookieOptions) *http.Cookie {
+	ck := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		SameSite: opts.SameSite,
+		Secure:   opts.Secure || c.IsHTTPS(),
+		HttpOnly: !opts.AllowScript,
+	}
+	if ck.Path == "" {
+		ck.Path = "/"
+	}
+	if ck.SameSite == 0 {
+		ck.SameSite = http.SameSiteLaxMode
+	}
+	if ck.SameSite == http.SameSiteNoneMode {
+		ck.Secure = true
+	}
+	return ck
+}
+
+// IsHTTPS 请求是否是https，在代理后面时看 X-Forwarded-Proto
+func (c *Context) IsHTTPS() bool {
+	if c.R.TLS != nil {
+		return true
+	}
+	return strings.EqualFold(c.R.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// BeforeWrite 注册响应头发送之前调用的函数，比如session在这里写cookie，按注册的顺序调用
+// 连接被Hijack或者响应头已经发送时不会再调用
+func (c *Context) BeforeWrite(fn func()) {
+	c.rw.beforeWrite = append(c.rw.beforeWrite, fn)
+}
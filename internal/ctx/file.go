@@ -0,0 +1,109 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ServeContent 发送content，Range、If-Range、If-None-Match、If-Modified-Since 等交给 http.ServeContent 处理
+// name 用来按后缀推断 Content-Type，modtime 为零值时不发送 Last-Modified
+// 没有设置 ETag 时，modtime 不为零值的话用修改时间和大小生成 ETag
+func (c *Context) ServeContent(name string, modtime time.Time, content io.ReadSeeker) {
+	h := c.W.Header()
+	if h.Get("ETag") == "" && !modtime.IsZero() {
+		if size, err := content.Seek(0, io.SeekEnd); err == nil {
+			if _, err := content.Seek(0, io.SeekStart); err == nil {
+				h.Set("ETag", fmt.Sprintf(`"%x-%x"`, modtime.UnixNano(), size))
+			}
+		}
+	}
+	http.ServeContent(c.W, c.R, name, modtime, content)
+}
+
+// Attachment 以附件的形式发送content，浏览器会按filename下载
+func (c *Context) Attachment(filename string, modtime time.Time, content io.ReadSeeker) {
+	c.W.Header().Set("Content-Disposition", contentDisposition("attachment", filename))
+	c.ServeContent(filename, modtime, content)
+}
+
+// File 在浏览器中直接展示本地文件，文件不存在或者是目录时返回错误，由调用方决定响应
+func (c *Context) File(path string) error {
+	return c.serveFile(path, "")
+}
+
+// FileAttachment 以附件的形式发送本地文件，filename为空时使用文件名
+//
+//	if err := c.FileAttachment(report, "report-2022.csv"); err != nil {
+//		c.W.WriteHeader(http.StatusNotFound)
+//	}
+func (c *Context) FileAttachment(path, filename string) error {
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	return c.serveFile(path, filename)
+}
+
+func (c *Context) serveFile(path, attachment string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New("ctx: " + path + " is a directory")
+	}
+
+	if attachment != "" {
+		c.Attachment(attachment, info.ModTime(), f)
+		return nil
+	}
+	c.ServeContent(info.Name(), info.ModTime(), f)
+	return nil
+}
+
+// contentDisposition filename 用于不支持 RFC 5987 的客户端，非ASCII字符替换成 _
+// filename* 保存UTF-8编码的完整文件名
+func contentDisposition(typ, filename string) string {
+	var b strings.Builder
+	for _, r := range filename {
+		switch {
+		case r < 0x20 || r >= 0x7f || r == '"' || r == '\\':
+			b.WriteByte('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	res := fmt.Sprintf(`%s; filename="%s"`, typ, b.String())
+	if b.String() != filename {
+		res += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return res
+}
+
+// encodeExtValue RFC 5987 的 attr-char 之外的字节都用百分号编码
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch < 0x80 && (ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' ||
+			strings.IndexByte("!#$&+-.^_`|~", ch) >= 0) {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&0xf])
+	}
+	return b.String()
+}
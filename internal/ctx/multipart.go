@@ -0,0 +1,381 @@
+package ctx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrFileTooLarge 上传的单个文件超过 MultipartOptions.MaxFileSize
+	ErrFileTooLarge = errors.New("ctx: uploaded file too large")
+	// ErrFileTypeNotAllowed 上传文件探测出的类型不在 MultipartOptions.AllowedTypes 中
+	ErrFileTypeNotAllowed = errors.New("ctx: uploaded file type not allowed")
+	// ErrMissingFile 表单中没有对应的文件
+	ErrMissingFile = errors.New("ctx: no such file")
+)
+
+// sniffLen http.DetectContentType 最多使用的字节数
+const sniffLen = 512
+
+// MultipartOptions 解析multipart表单的配置，作为 BodyOptions 的一部分设置
+type MultipartOptions struct {
+	// MaxMemory 表单在内存中保存的最大字节数，超过的文件写到临时文件，<=0 时为32M
+	MaxMemory int64
+	// MaxFileSize 单个文件的最大字节数，<=0 时不限制，整个body的大小仍然受 MaxBytes 限制
+	MaxFileSize int64
+	// TempDir 临时文件的目录，为空时使用 os.TempDir()
+	TempDir string
+	// AllowedTypes 允许上传的文件类型，按内容探测，比如 image/png，为空时不限制
+	AllowedTypes []string
+}
+
+func (o *MultipartOptions) maxMemory() int64 {
+	if o.MaxMemory > 0 {
+		return o.MaxMemory
+	}
+	return defaultMultipartMemory
+}
+
+// checkType 探测的类型可能带参数，比如 text/plain; charset=utf-8，只比较前面的部分
+func (o *MultipartOptions) checkType(filename, ctype string) error {
+	if len(o.AllowedTypes) == 0 {
+		return nil
+	}
+	mt := strings.TrimSpace(strings.Split(ctype, ";")[0])
+	for _, t := range o.AllowedTypes {
+		if t == mt {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is %s", ErrFileTypeNotAllowed, filename, mt)
+}
+
+// FormFile 上传的文件，小文件保存在内存中，大文件保存在临时文件中
+// 临时文件在请求结束后由路由删除，需要保留的话调用 Save
+type FormFile struct {
+	Field    string
+	Filename string
+	Header   textproto.MIMEHeader
+	Size     int64
+	// ContentType 按文件内容探测出的类型，客户端声明的类型见 Header
+	ContentType string
+
+	content []byte
+	// path 文件在磁盘上的路径，tmp为true时请求结束后删除
+	path string
+	tmp  bool
+}
+
+// Open 打开文件读取内容
+func (f *FormFile) Open() (multipart.File, error) {
+	if f.path != "" {
+		return os.Open(f.path)
+	}
+	return nopCloser{bytes.NewReader(f.content)}, nil
+}
+
+// savedFileMode Save 之后文件的权限，CreateTemp 创建的文件是0600
+const savedFileMode = 0644
+
+// Save 原子地把文件保存到dst：先写到dst所在目录的临时文件，再rename，保存后的权限都是0644
+// 文件在临时文件中并且和dst在同一个文件系统的话直接rename，不再复制
+func (f *FormFile) Save(dst string) error {
+	if f.tmp {
+		// rename之前修改权限，rename之后dst一出现就是最终的权限
+		if err := os.Chmod(f.path, savedFileMode); err != nil {
+			return err
+		}
+		if err := os.Rename(f.path, dst); err == nil {
+			f.path, f.tmp = dst, false
+			return nil
+		}
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), savedFileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// MultipartForm 解析后的multipart表单
+type MultipartForm struct {
+	Value map[string][]string
+	File  map[string][]*FormFile
+}
+
+// RemoveAll 删除所有的临时文件
+func (f *MultipartForm) RemoveAll() error {
+	var err error
+	for _, fs := range f.File {
+		for _, fh := range fs {
+			if !fh.tmp {
+				continue
+			}
+			if e := os.Remove(fh.path); e != nil && !errors.Is(e, os.ErrNotExist) && err == nil {
+				err = e
+			}
+			fh.tmp = false
+		}
+	}
+	return err
+}
+
+// MultipartForm 解析multipart表单，只解析一次，配置见 BodyOptions.Multipart
+// 普通字段同时放到 c.R.PostForm 中
+func (c *Context) MultipartForm() (*MultipartForm, error) {
+	if c.form == nil && c.formErr == nil {
+		c.form, c.formErr = c.parseMultipart()
+		if c.form != nil {
+			c.R.PostForm = c.form.Value
+		}
+	}
+	return c.form, c.formErr
+}
+
+// FormFile 返回表单中name对应的第一个文件
+func (c *Context) FormFile(name string) (*FormFile, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	fs := form.File[name]
+	if len(fs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingFile, name)
+	}
+	return fs[0], nil
+}
+
+func (c *Context) parseMultipart() (_ *MultipartForm, err error) {
+	c.Body()
+	mr, err := c.R.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	opts := &c.bodyOpts.Multipart
+
+	form := &MultipartForm{
+		Value: make(map[string][]string),
+		File:  make(map[string][]*FormFile),
+	}
+	// 出错时删除已经写出的临时文件
+	defer func() {
+		if err != nil {
+			form.RemoveAll()
+		}
+	}()
+
+	memLeft := opts.maxMemory()
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+
+		if p.FileName() == "" {
+			var b bytes.Buffer
+			n, err := io.CopyN(&b, p, memLeft+1)
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if memLeft -= n; memLeft < 0 {
+				return nil, multipart.ErrMessageTooLarge
+			}
+			form.Value[name] = append(form.Value[name], b.String())
+			continue
+		}
+
+		fh := &FormFile{Field: name, Filename: p.FileName(), Header: p.Header}
+		form.File[name] = append(form.File[name], fh)
+		if err := readFormFile(fh, p, opts, &memLeft); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readFormFile 读取一个文件，内存不够时剩余内容写到临时文件
+func readFormFile(fh *FormFile, p *multipart.Part, opts *MultipartOptions, memLeft *int64) error {
+	br := bufio.NewReaderSize(p, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	fh.ContentType = http.DetectContentType(head)
+	if err := opts.checkType(fh.Filename, fh.ContentType); err != nil {
+		return err
+	}
+
+	r := limitFile(br, fh.Filename, opts.MaxFileSize)
+	var b bytes.Buffer
+	n, err := io.CopyN(&b, r, *memLeft+1)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if n <= *memLeft {
+		fh.content = b.Bytes()
+		fh.Size = n
+		*memLeft -= n
+		return nil
+	}
+
+	file, err := os.CreateTemp(opts.TempDir, "multipart-")
+	if err != nil {
+		return err
+	}
+	fh.path, fh.tmp = file.Name(), true
+	size, err := io.Copy(file, io.MultiReader(&b, r))
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	fh.Size = size
+	return err
+}
+
+// Part 流式读取multipart时的一个字段或者文件
+type Part struct {
+	FormName string
+	// FileName 普通字段为空
+	FileName string
+	Header   textproto.MIMEHeader
+	// ContentType 文件按内容探测出的类型，普通字段为空
+	ContentType string
+
+	r io.Reader
+}
+
+func (p *Part) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+// IsFile 是否是文件
+func (p *Part) IsFile() bool {
+	return p.FileName != ""
+}
+
+// PartReader 流式读取multipart，内容不会缓存到内存或者磁盘，适合大文件直接转存
+//
+//	pr, err := c.MultipartReader()
+//	for {
+//		part, err := pr.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+type PartReader struct {
+	mr   *multipart.Reader
+	opts *MultipartOptions
+}
+
+// MultipartReader 返回流式读取body的 PartReader，和 MultipartForm 只能使用一个
+func (c *Context) MultipartReader() (*PartReader, error) {
+	c.Body()
+	mr, err := c.R.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	return &PartReader{mr: mr, opts: &c.bodyOpts.Multipart}, nil
+}
+
+// Next 返回下一个part，没有时返回 io.EOF，之前的part没有读完的话会被跳过
+// 文件超过 MaxFileSize 时读取返回 ErrFileTooLarge，类型不允许时返回 ErrFileTypeNotAllowed
+func (r *PartReader) Next() (*Part, error) {
+	for {
+		p, err := r.mr.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if p.FormName() == "" {
+			continue
+		}
+		part := &Part{
+			FormName: p.FormName(),
+			FileName: p.FileName(),
+			Header:   p.Header,
+			r:        p,
+		}
+		if !part.IsFile() {
+			return part, nil
+		}
+
+		br := bufio.NewReaderSize(p, sniffLen)
+		head, _ := br.Peek(sniffLen)
+		part.ContentType = http.DetectContentType(head)
+		if err := r.opts.checkType(part.FileName, part.ContentType); err != nil {
+			return nil, err
+		}
+		part.r = limitFile(br, part.FileName, r.opts.MaxFileSize)
+		return part, nil
+	}
+}
+
+// limitFile max<=0 时不限制
+func limitFile(r io.Reader, filename string, max int64) io.Reader {
+	if max <= 0 {
+		return r
+	}
+	return &fileLimitReader{r: r, filename: filename, left: max}
+}
+
+type fileLimitReader struct {
+	r        io.Reader
+	filename string
+	left     int64
+}
+
+// Read 多读一个字节判断是否超过限制
+func (l *fileLimitReader) Read(b []byte) (int, error) {
+	if int64(len(b)) > l.left+1 {
+		b = b[:l.left+1]
+	}
+	n, err := l.r.Read(b)
+	if int64(n) > l.left {
+		n = int(l.left)
+		l.left = 0
+		return n, fmt.Errorf("%w: %s", ErrFileTooLarge, l.filename)
+	}
+	l.left -= int64(n)
+	return n, err
+}
@@ -0,0 +1,40 @@
+package ctx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormFileSaveMode(t *testing.T) {
+	dir := t.TempDir()
+	tmp, err := os.CreateTemp(dir, "multipart-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp.WriteString("on disk")
+	tmp.Close()
+
+	tests := []struct {
+		name string
+		file *FormFile
+	}{
+		{name: "rename temp file", file: &FormFile{path: tmp.Name(), tmp: true}},
+		{name: "copy from memory", file: &FormFile{content: []byte("in memory")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(dir, "saved-"+filepath.Base(t.Name()))
+			if err := tt.file.Save(dst); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != savedFileMode {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(savedFileMode))
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package ctx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ParamError 查询参数或路径参数转换失败的信息
+// 类型转换的方法出错时返回默认值，错误记录在Context上，handler最后统一检查 ParamErrors
+type ParamError struct {
+	// Source query 或者 path
+	Source string `json:"source"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid %s param %s=%q: %s", e.Source, e.Key, e.Value, e.Reason)
+}
+
+// ParamErrors 返回目前为止所有转换失败的参数
+func (c *Context) ParamErrors() []*ParamError {
+	return c.paramErrs
+}
+
+func (c *Context) addParamError(source, key, value string, err error) {
+	// strconv 的错误信息里已经有值了，只保留原因
+	var ne *strconv.NumError
+	if errors.As(err, &ne) {
+		err = ne.Err
+	}
+	c.paramErrs = append(c.paramErrs, &ParamError{
+		Source: source,
+		Key:    key,
+		Value:  value,
+		Reason: err.Error(),
+	})
+}
+
+// queryValues 第一次访问时解析query，之后复用
+func (c *Context) queryValues() url.Values {
+	if c.query == nil {
+		c.query = c.R.URL.Query()
+	}
+	return c.query
+}
+
+// Query 返回query参数，不存在时返回空
+func (c *Context) Query(key string) string {
+	return c.queryValues().Get(key)
+}
+
+// GetQuery 返回query参数以及是否存在
+func (c *Context) GetQuery(key string) (string, bool) {
+	vs, ok := c.queryValues()[key]
+	if !ok || len(vs) == 0 {
+		return "", false
+	}
+	return vs[0], true
+}
+
+// DefaultQuery query参数不存在或者为空时返回def
+func (c *Context) DefaultQuery(key, def string) string {
+	if v := c.Query(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// QueryArray 返回同名的所有query参数，比如 ?id=1&id=2
+func (c *Context) QueryArray(key string) []string {
+	return c.queryValues()[key]
+}
+
+// QueryInt 参数不存在或者为空时返回def，转换失败时记录错误并返回def
+func (c *Context) QueryInt(key string, def int) int {
+	v := c.Query(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		c.addParamError("query", key, v, err)
+		return def
+	}
+	return n
+}
+
+// QueryInt64 同 QueryInt
+func (c *Context) QueryInt64(key string, def int64) int64 {
+	v := c.Query(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		c.addParamError("query", key, v, err)
+		return def
+	}
+	return n
+}
+
+// QueryFloat64 同 QueryInt
+func (c *Context) QueryFloat64(key string, def float64) float64 {
+	v := c.Query(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		c.addParamError("query", key, v, err)
+		return def
+	}
+	return f
+}
+
+// QueryBool 支持 strconv.ParseBool 的写法，比如 1、true、false
+func (c *Context) QueryBool(key string, def bool) bool {
+	v := c.Query(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		c.addParamError("query", key, v, err)
+		return def
+	}
+	return b
+}
+
+// QueryDuration 支持 time.ParseDuration 的写法，比如 300ms、1m30s
+func (c *Context) QueryDuration(key string, def time.Duration) time.Duration {
+	v := c.Query(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		c.addParamError("query", key, v, err)
+		return def
+	}
+	return d
+}
+
+// QueryInts 把同名的所有query参数转成int，有一个失败就记录错误并返回nil
+func (c *Context) QueryInts(key string) []int {
+	vs := c.QueryArray(key)
+	if len(vs) == 0 {
+		return nil
+	}
+	res := make([]int, 0, len(vs))
+	for _, v := range vs {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.addParamError("query", key, v, err)
+			return nil
+		}
+		res = append(res, n)
+	}
+	return res
+}
+
+// ParamInt 把路径参数转成int，参数不存在或者转换失败时记录错误并返回0
+func (c *Context) ParamInt(key string) int {
+	v, ok := c.Params.Get(key)
+	if !ok {
+		c.addParamError("path", key, v, errors.New("missing"))
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		c.addParamError("path", key, v, err)
+		return 0
+	}
+	return n
+}
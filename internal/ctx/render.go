@@ -0,0 +1,314 @@
+package ctx
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Renderer 把响应编码成某种格式
+type Renderer interface {
+	// ContentType 响应的 Content-Type
+	ContentType() string
+	// Render 编码data，不支持data的类型时返回 ErrNotRenderable，会尝试下一个可接受的格式
+	Render(w io.Writer, data interface{}) error
+}
+
+// BodyDecoder 按某种格式解析请求body，需要通过 c.Body() 读取保证大小限制
+type BodyDecoder func(c *Context, v interface{}) error
+
+// ErrNotRenderable Renderer 不支持编码data的类型
+var ErrNotRenderable = errors.New("ctx: data not renderable")
+
+// ErrNotAcceptable Accept 中没有能编码data的格式，Render 已经返回了406
+var ErrNotAcceptable = errors.New("ctx: not acceptable")
+
+const (
+	MIMEJSON      = "application/json"
+	MIMEXML       = "application/xml"
+	MIMEXML2      = "text/xml"
+	MIMEMsgPack   = "application/msgpack"
+	MIMEMsgPack2  = "application/x-msgpack"
+	MIMEProtobuf  = "application/x-protobuf"
+	MIMEForm      = "application/x-www-form-urlencoded"
+	MIMEMultipart = "multipart/form-data"
+)
+
+// 注册表需要在启动前注册好，运行时只读
+var (
+	renderers = make(map[string]Renderer)
+	// renderOrder 按注册顺序记录，Accept 为 */* 等通配符时按这个顺序选择
+	renderOrder []string
+	decoders    = make(map[string]BodyDecoder)
+)
+
+func init() {
+	RegisterRenderer(MIMEJSON, jsonRenderer{})
+	RegisterRenderer(MIMEXML, xmlRenderer{MIMEXML})
+	RegisterRenderer(MIMEXML2, xmlRenderer{MIMEXML2})
+	RegisterRenderer(MIMEMsgPack, msgpackRenderer{MIMEMsgPack})
+	RegisterRenderer(MIMEMsgPack2, msgpackRenderer{MIMEMsgPack2})
+	RegisterRenderer(MIMEProtobuf, protobufRenderer{})
+
+	RegisterDecoder(MIMEJSON, decodeJsonBody)
+	RegisterDecoder(MIMEXML, decodeXmlBody)
+	RegisterDecoder(MIMEXML2, decodeXmlBody)
+	RegisterDecoder(MIMEMsgPack, decodeMsgpackBody)
+	RegisterDecoder(MIMEMsgPack2, decodeMsgpackBody)
+	RegisterDecoder(MIMEProtobuf, decodeProtobufBody)
+	RegisterDecoder(MIMEForm, decodeFormBody)
+	RegisterDecoder(MIMEMultipart, decodeMultipartBody)
+}
+
+// RegisterRenderer 注册或者替换mediaType对应的Renderer，第一个注册的作为默认格式
+func RegisterRenderer(mediaType string, r Renderer) {
+	if _, ok := renderers[mediaType]; !ok {
+		renderOrder = append(renderOrder, mediaType)
+	}
+	renderers[mediaType] = r
+}
+
+// RegisterDecoder 注册或者替换 Content-Type 对应的解析函数，Bind 时使用
+func RegisterDecoder(mediaType string, d BodyDecoder) {
+	decoders[mediaType] = d
+}
+
+// decoderFor 没有精确匹配时，+json、+xml 结尾的类型按JSON、XML解析
+func decoderFor(mediaType string) (BodyDecoder, bool) {
+	if d, ok := decoders[mediaType]; ok {
+		return d, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		d, ok := decoders[MIMEJSON]
+		return d, ok
+	case strings.HasSuffix(mediaType, "+xml"):
+		d, ok := decoders[MIMEXML]
+		return d, ok
+	}
+	return nil, false
+}
+
+// Render 按 Accept 选择格式编码data并写回，依次尝试可接受的格式，都不支持时使用默认格式
+// 默认格式也被 q=0 排除的话返回406以及 ErrNotAcceptable
+// 先编码到buffer，成功之后再写状态码，编码失败时不会写出任何内容
+func (c *Context) Render(code int, data interface{}) error {
+	var buf bytes.Buffer
+	for _, mt := range c.acceptedTypes() {
+		r := renderers[mt]
+		buf.Reset()
+		err := r.Render(&buf, data)
+		if errors.Is(err, ErrNotRenderable) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		c.W.Header().Set("Content-Type", r.ContentType())
+		c.W.Header().Add("Vary", "Accept")
+		c.W.WriteHeader(code)
+		_, err = c.W.Write(buf.Bytes())
+		return err
+	}
+	c.W.WriteHeader(http.StatusNotAcceptable)
+	return fmt.Errorf("%w: %T", ErrNotAcceptable, data)
+}
+
+// acceptedTypes 按 q 值从高到低返回可以使用的已注册格式，默认格式没有被 q=0 排除的话加在最后兜底
+func (c *Context) acceptedTypes() []string {
+	var res []string
+	seen := make(map[string]bool)
+	add := func(mt string) {
+		if !seen[mt] {
+			seen[mt] = true
+			res = append(res, mt)
+		}
+	}
+
+	items := parseAccept(c.R.Header.Get("Accept"))
+	// q=0 表示明确不接受，通配符展开时跳过
+	excluded := make(map[string]bool)
+	for _, a := range items {
+		if a.q <= 0 {
+			seen[a.typ] = true
+			excluded[a.typ] = true
+		}
+	}
+	for _, a := range items {
+		switch {
+		case a.q <= 0:
+		case a.typ == "*/*":
+			for _, mt := range renderOrder {
+				add(mt)
+			}
+		case strings.HasSuffix(a.typ, "/*"):
+			prefix := strings.TrimSuffix(a.typ, "*")
+			for _, mt := range renderOrder {
+				if strings.HasPrefix(mt, prefix) {
+					add(mt)
+				}
+			}
+		default:
+			if _, ok := renderers[a.typ]; ok {
+				add(a.typ)
+			}
+		}
+	}
+	if len(renderOrder) > 0 && !excluded[renderOrder[0]] {
+		res = append(res, renderOrder[0])
+	}
+	return res
+}
+
+type acceptItem struct {
+	typ string
+	q   float64
+}
+
+// parseAccept 解析 Accept 头，按q值稳定排序
+func parseAccept(accept string) []acceptItem {
+	var items []acceptItem
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mt, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		items = append(items, acceptItem{typ: mt, q: q})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+	return items
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) ContentType() string {
+	return MIMEJSON + "; charset=utf-8"
+}
+
+func (jsonRenderer) Render(w io.Writer, data interface{}) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+type xmlRenderer struct {
+	mediaType string
+}
+
+func (r xmlRenderer) ContentType() string {
+	return r.mediaType + "; charset=utf-8"
+}
+
+// Render xml不支持map等类型，编码失败时换其他格式
+func (r xmlRenderer) Render(w io.Writer, data interface{}) error {
+	err := xml.NewEncoder(w).Encode(data)
+	var ue *xml.UnsupportedTypeError
+	if errors.As(err, &ue) {
+		return fmt.Errorf("%w: %v", ErrNotRenderable, err)
+	}
+	return err
+}
+
+type msgpackRenderer struct {
+	mediaType string
+}
+
+func (r msgpackRenderer) ContentType() string {
+	return r.mediaType
+}
+
+// Render 使用json标签，和JSON的字段名保持一致
+func (msgpackRenderer) Render(w io.Writer, data interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(data)
+}
+
+type protobufRenderer struct{}
+
+func (protobufRenderer) ContentType() string {
+	return MIMEProtobuf
+}
+
+// Render 只支持 proto.Message
+func (protobufRenderer) Render(w io.Writer, data interface{}) error {
+	m, ok := data.(proto.Message)
+	if !ok {
+		return ErrNotRenderable
+	}
+	buf, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func decodeJsonBody(c *Context, v interface{}) error {
+	if err := c.decodeJson(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func decodeXmlBody(c *Context, v interface{}) error {
+	if err := xml.NewDecoder(c.Body()).Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func decodeMsgpackBody(c *Context, v interface{}) error {
+	dec := msgpack.NewDecoder(c.Body())
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(c.bodyOpts.DisallowUnknownFields)
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func decodeProtobufBody(c *Context, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("ctx: %T is not a proto.Message", v)
+	}
+	buf, err := io.ReadAll(c.Body())
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(buf, m)
+}
+
+// decodeFormBody 只解析表单，由 Bind 按form标签取值
+func decodeFormBody(c *Context, v interface{}) error {
+	c.Body()
+	return c.R.ParseForm()
+}
+
+// decodeMultipartBody 文件字段由 Bind 按form标签取值，字段类型为 *FormFile 或者 []*FormFile
+func decodeMultipartBody(c *Context, v interface{}) error {
+	_, err := c.MultipartForm()
+	return err
+}
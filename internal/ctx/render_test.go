@@ -0,0 +1,49 @@
+package ctx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type renderUser struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestRenderAccept(t *testing.T) {
+	tests := []struct {
+		accept   string
+		wantCode int
+		wantType string
+		wantErr  error
+	}{
+		{accept: "", wantCode: http.StatusOK, wantType: MIMEJSON},
+		{accept: "application/xml", wantCode: http.StatusOK, wantType: MIMEXML},
+		{accept: "text/html", wantCode: http.StatusOK, wantType: MIMEJSON},
+		{accept: "*/*;q=0.5, application/json;q=0", wantCode: http.StatusOK, wantType: MIMEXML},
+		{accept: "application/json;q=0", wantCode: http.StatusNotAcceptable, wantErr: ErrNotAcceptable},
+		{accept: "text/html, application/json;q=0", wantCode: http.StatusNotAcceptable, wantErr: ErrNotAcceptable},
+	}
+	data := renderUser{Name: "tom"}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			err := NewContext(w, r).Render(http.StatusOK, data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+		})
+	}
+}
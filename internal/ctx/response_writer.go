@@ -0,0 +1,139 @@
+package ctx
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter 记录状态码、body大小以及header是否已经发送的 http.ResponseWriter
+// NewContext 时自动包装，通过 c.Response() 获取，中间件在 c.Next() 之后可以拿到handler的结果
+// 同时实现了 http.Flusher、http.Hijacker、http.Pusher，底层不支持时分别为空操作或者返回错误
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	// Status 返回写出的状态码，还没有写出时返回200
+	Status() int
+	// Size 返回已经写出的body字节数
+	Size() int64
+	// Written 返回header是否已经发送
+	Written() bool
+	// Unwrap 返回被包装的 http.ResponseWriter
+	Unwrap() http.ResponseWriter
+}
+
+var errHijackNotSupported = errors.New("ctx: response writer does not support hijacking")
+
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int64
+	written bool
+	// 发送响应头之前调用，见 Context.BeforeWrite
+	beforeWrite []func()
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+}
+
+// WriteHeader 1xx的状态码(101除外)可以发送多次，不算写出了header
+func (w *responseWriter) WriteHeader(code int) {
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.written {
+		return
+	}
+	// 先置空，回调里再写响应时不会重复调用
+	fns := w.beforeWrite
+	w.beforeWrite = nil
+	for _, fn := range fns {
+		fn()
+	}
+	if w.written {
+		return
+	}
+	w.status = code
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// ReadFrom 底层支持的话使用 io.ReaderFrom，http.ServeContent 等可以用上sendfile
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 之后连接由调用方接管，状态码记为101
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !w.written {
+		w.status = http.StatusSwitchingProtocols
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int64 {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
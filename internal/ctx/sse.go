@@ -0,0 +1,188 @@
+package ctx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSSEKeepAlive SSE发送keepalive注释的间隔，避免代理因为空闲断开连接
+var DefaultSSEKeepAlive = 15 * time.Second
+
+// ErrStreamingNotSupported 底层的 ResponseWriter 不支持Flush
+var ErrStreamingNotSupported = errors.New("ctx: streaming not supported")
+
+// ErrStreamClosed 流已经结束，客户端断开、服务关闭或者调用了Close
+var ErrStreamClosed = errors.New("ctx: stream closed")
+
+// SSEvent 一个Server-Sent Event，空的字段不发送
+type SSEvent struct {
+	ID    string
+	Event string
+	// Data string和[]byte原样发送，多行时拆成多个data字段，其他类型编码成JSON
+	Data interface{}
+	// Retry 客户端断线重连的间隔
+	Retry time.Duration
+}
+
+// SSEStream Server-Sent Events 的写入端，可以在多个goroutine中使用
+type SSEStream struct {
+	c       *Context
+	flusher http.Flusher
+
+	mu     sync.Mutex
+	closed bool
+	// done 客户端断开、服务开始关闭或者调用Close时关闭
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// SSE 设置 text/event-stream 的响应头并返回写入端，之后按 DefaultSSEKeepAlive 的间隔发送keepalive
+// 客户端断开或者服务开始关闭(见 Draining)时 Done 会被关闭，handler应该尽快返回
+//
+//	s, err := c.SSE()
+//	if err != nil {
+//		return
+//	}
+//	defer s.Close()
+//	for {
+//		select {
+//		case <-s.Done():
+//			return
+//		case msg := <-ch:
+//			if err := s.Send(ctx.SSEvent{Event: "message", Data: msg}); err != nil {
+//				return
+//			}
+//		}
+//	}
+func (c *Context) SSE() (*SSEStream, error) {
+	if _, ok := c.rw.Unwrap().(http.Flusher); !ok {
+		return nil, ErrStreamingNotSupported
+	}
+	flusher, ok := c.W.(http.Flusher)
+	if !ok {
+		flusher = c.rw
+	}
+
+	h := c.W.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// 禁止nginx缓冲
+	h.Set("X-Accel-Buffering", "no")
+	c.W.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	s := &SSEStream{
+		c:       c,
+		flusher: flusher,
+		done:    make(chan struct{}),
+	}
+	c.sse = s
+	go s.keepAlive(DefaultSSEKeepAlive, c.Done(), c.Draining())
+	return s, nil
+}
+
+// keepAlive 定时发送注释，同时监听客户端断开以及服务关闭
+func (s *SSEStream) keepAlive(interval time.Duration, reqDone, draining <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Comment("keepalive"); err != nil {
+				s.Close()
+				return
+			}
+		case <-reqDone:
+			s.Close()
+			return
+		case <-draining:
+			s.Close()
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Done 流结束时关闭
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close 结束流，不会关闭连接，handler返回之后响应才结束
+func (s *SSEStream) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// Send 发送一个事件并立即Flush
+func (s *SSEStream) Send(e SSEvent) error {
+	var b bytes.Buffer
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", sseField(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", sseField(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+	if e.Data != nil {
+		data, err := sseData(e.Data)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(data, "\n") {
+			fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+		}
+	}
+	b.WriteByte('\n')
+	return s.write(b.Bytes())
+}
+
+// Comment 发送注释，客户端会忽略
+func (s *SSEStream) Comment(text string) error {
+	return s.write([]byte(": " + sseField(text) + "\n\n"))
+}
+
+func (s *SSEStream) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	if _, err := s.c.W.Write(b); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// sseField id、event 等字段不能包含换行
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func sseData(v interface{}) (string, error) {
+	switch d := v.(type) {
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,75 @@
+package ctx
+
+import (
+	"fmt"
+	"time"
+)
+
+// Set 保存一个请求级别的值，用于在中间件和handler之间传递数据，可以并发调用
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[string]interface{})
+	}
+	c.keys[key] = value
+}
+
+// Get 返回Set保存的值，不存在时返回false
+func (c *Context) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.keys[key]
+	return v, ok
+}
+
+// MustGet 值不存在时panic，用于中间件一定会设置的值
+func (c *Context) MustGet(key string) interface{} {
+	v, ok := c.Get(key)
+	if !ok {
+		panic(fmt.Sprintf("ctx: key %s does not exist", key))
+	}
+	return v
+}
+
+// GetString 值不存在或者类型不对时返回空
+func (c *Context) GetString(key string) string {
+	v, _ := c.Get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// GetInt 同 GetString
+func (c *Context) GetInt(key string) int {
+	v, _ := c.Get(key)
+	n, _ := v.(int)
+	return n
+}
+
+// GetInt64 同 GetString
+func (c *Context) GetInt64(key string) int64 {
+	v, _ := c.Get(key)
+	n, _ := v.(int64)
+	return n
+}
+
+// GetBool 同 GetString
+func (c *Context) GetBool(key string) bool {
+	v, _ := c.Get(key)
+	b, _ := v.(bool)
+	return b
+}
+
+// GetDuration 同 GetString
+func (c *Context) GetDuration(key string) time.Duration {
+	v, _ := c.Get(key)
+	d, _ := v.(time.Duration)
+	return d
+}
+
+// GetTime 同 GetString
+func (c *Context) GetTime(key string) time.Time {
+	v, _ := c.Get(key)
+	t, _ := v.(time.Time)
+	return t
+}
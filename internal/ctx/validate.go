@@ -0,0 +1,295 @@
+package ctx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError 绑定或者校验失败的字段
+type FieldError struct {
+	// Field 字段在请求中的名字，嵌套的字段形如 msgs[0].key
+	Field string `json:"field"`
+	// Rule 不满足的规则，类型转换失败时为 type
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// FieldErrors Bind 和 Validate 返回的错误，包含所有失败的字段
+type FieldErrors []*FieldError
+
+func (es FieldErrors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// rule validate 标签中的一条规则
+type rule struct {
+	name string
+	arg  string
+	num  float64
+	set  []string
+	re   *regexp.Regexp
+}
+
+// parseRules 解析 validate 标签，规则之间用逗号分隔
+// regex 的参数可能包含逗号，所以只能放在最后，之后的内容都作为正则
+// 标签写错属于代码问题，直接panic
+func parseRules(field, tag string) []rule {
+	var rules []rule
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "regex=") {
+			item, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			item, tag = tag[:i], tag[i+1:]
+		} else {
+			item, tag = tag, ""
+		}
+
+		r := rule{name: item}
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			r.name, r.arg = item[:i], item[i+1:]
+		}
+		switch r.name {
+		case "required":
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(r.arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("invalid validate rule %s on field %s", item, field))
+			}
+			r.num = n
+		case "oneof":
+			r.set = strings.Fields(r.arg)
+			if len(r.set) == 0 {
+				panic(fmt.Sprintf("invalid validate rule %s on field %s", item, field))
+			}
+		case "regex":
+			re, err := regexp.Compile(r.arg)
+			if err != nil {
+				panic(fmt.Sprintf("invalid validate rule %s on field %s, err:%v", item, field, err))
+			}
+			r.re = re
+		default:
+			panic(fmt.Sprintf("unknown validate rule %s on field %s", item, field))
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// check 空字符串、nil slice等零值只检查 required，数字的0也是有效值，所有规则都会检查
+// 可选的数字字段用指针类型，nil时只检查 required
+func (r *rule) check(v reflect.Value) string {
+	if r.name == "required" {
+		if v.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.IsZero() && !isNumber(v) {
+		return ""
+	}
+
+	switch r.name {
+	case "min", "max", "len":
+		n, isLen, ok := measure(v)
+		if !ok {
+			return fmt.Sprintf("rule %s not supported for %s", r.name, v.Type())
+		}
+		unit := ""
+		if isLen {
+			unit = " in length"
+		}
+		switch {
+		case r.name == "min" && n < r.num:
+			return fmt.Sprintf("must be at least %s%s", r.arg, unit)
+		case r.name == "max" && n > r.num:
+			return fmt.Sprintf("must be at most %s%s", r.arg, unit)
+		case r.name == "len" && n != r.num:
+			return fmt.Sprintf("must be exactly %s%s", r.arg, unit)
+		}
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, o := range r.set {
+			if s == o {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s]", r.arg)
+	case "regex":
+		if v.Kind() != reflect.String {
+			return fmt.Sprintf("rule %s not supported for %s", r.name, v.Type())
+		}
+		if !r.re.MatchString(v.String()) {
+			return fmt.Sprintf("must match %s", r.arg)
+		}
+	}
+	return ""
+}
+
+// measure 数字比较值，字符串比较字符数，slice和map比较长度
+func measure(v reflect.Value) (n float64, isLen, ok bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			return float64(v.Int()), false, false
+		}
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	}
+	return 0, false, false
+}
+
+func isNumber(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldSpec 结构体字段的标签信息，按类型缓存
+type fieldSpec struct {
+	index int
+	// name 出错时展示的名字，依次取 path、query、header、form、json 标签，都没有时用字段名
+	name   string
+	path   string
+	query  string
+	header string
+	form   string
+	rules  []rule
+}
+
+var structSpecs sync.Map // reflect.Type -> []fieldSpec
+
+func specsOf(t reflect.Type) []fieldSpec {
+	if v, ok := structSpecs.Load(t); ok {
+		return v.([]fieldSpec)
+	}
+	specs := make([]fieldSpec, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		s := fieldSpec{
+			index:  i,
+			path:   f.Tag.Get("path"),
+			query:  f.Tag.Get("query"),
+			header: f.Tag.Get("header"),
+			form:   f.Tag.Get("form"),
+		}
+		s.name = f.Name
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		for _, n := range []string{s.path, s.query, s.header, s.form, jsonName} {
+			if n != "" && n != "-" {
+				s.name = n
+				break
+			}
+		}
+		s.rules = parseRules(t.Name()+"."+f.Name, f.Tag.Get("validate"))
+		specs = append(specs, s)
+	}
+	v, _ := structSpecs.LoadOrStore(t, specs)
+	return v.([]fieldSpec)
+}
+
+// Validate 按 validate 标签校验结构体，嵌套的结构体以及结构体的slice会递归校验
+//
+//	type Req struct {
+//		Topic string `json:"topic" validate:"required,max=249,regex=^[a-zA-Z0-9._-]+$"`
+//		Type  string `json:"type" validate:"oneof=direct fanout topic"`
+//		Msgs  []Msg  `json:"msgs" validate:"required,min=1"`
+//	}
+//
+// 校验失败时返回 FieldErrors
+func Validate(v interface{}) error {
+	var errs FieldErrors
+	validateValue(reflect.ValueOf(v), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, prefix string, errs *FieldErrors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		for _, s := range specsOf(v.Type()) {
+			fv := v.Field(s.index)
+			name := s.name
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			for i := range s.rules {
+				if reason := s.rules[i].check(fv); reason != "" {
+					*errs = append(*errs, &FieldError{Field: name, Rule: s.rules[i].name, Reason: reason})
+				}
+			}
+			validateValue(fv, name, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		if !hasNested(v.Type().Elem()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", prefix, i), errs)
+		}
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// hasNested 元素可能包含需要校验的结构体，避免遍历 []byte 这种大slice
+func hasNested(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return t != timeType
+	case reflect.Slice, reflect.Array, reflect.Interface:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package ctx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateZeroValues(t *testing.T) {
+	type req struct {
+		Page int      `query:"page" validate:"min=1"`
+		Size *int     `query:"size" validate:"min=1,max=100"`
+		Name string   `json:"name" validate:"min=2"`
+		Tags []string `json:"tags" validate:"required,min=1"`
+	}
+	intPtr := func(n int) *int { return &n }
+
+	tests := []struct {
+		name string
+		req  req
+		// wantFields 校验失败的字段，按出现的顺序
+		wantFields []string
+	}{
+		{name: "valid", req: req{Page: 1, Size: intPtr(10), Name: "ab", Tags: []string{"a"}}},
+		{name: "zero number checked", req: req{Page: 0, Tags: []string{"a"}}, wantFields: []string{"page"}},
+		{name: "nil pointer skipped", req: req{Page: 1, Size: nil, Tags: []string{"a"}}},
+		{name: "pointer to zero checked", req: req{Page: 1, Size: intPtr(0), Tags: []string{"a"}}, wantFields: []string{"size"}},
+		{name: "pointer over max", req: req{Page: 1, Size: intPtr(101), Tags: []string{"a"}}, wantFields: []string{"size"}},
+		{name: "empty string skipped", req: req{Page: 1, Name: "", Tags: []string{"a"}}},
+		{name: "short string", req: req{Page: 1, Name: "a", Tags: []string{"a"}}, wantFields: []string{"name"}},
+		{name: "nil slice required", req: req{Page: 1}, wantFields: []string{"tags"}},
+		{name: "empty slice min", req: req{Page: 1, Tags: []string{}}, wantFields: []string{"tags"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.req)
+			var fes FieldErrors
+			if err != nil && !errors.As(err, &fes) {
+				t.Fatalf("err = %v, want FieldErrors", err)
+			}
+			var got []string
+			for _, fe := range fes {
+				got = append(got, fe.Field)
+			}
+			if len(got) != len(tt.wantFields) {
+				t.Fatalf("fields = %v, want %v", got, tt.wantFields)
+			}
+			for i := range got {
+				if got[i] != tt.wantFields[i] {
+					t.Errorf("fields = %v, want %v", got, tt.wantFields)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,557 @@
+package ctx
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型，和 RFC 6455 的opcode一致
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// 关闭帧的状态码
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+var (
+	// ErrBadHandshake 请求不是合法的WebSocket握手
+	ErrBadHandshake = errors.New("ctx: websocket: bad handshake")
+	// ErrOriginNotAllowed Origin 没有通过 WebSocketOptions.CheckOrigin
+	ErrOriginNotAllowed = errors.New("ctx: websocket: origin not allowed")
+	// ErrWSMessageTooLarge 收到的消息超过 WebSocketOptions.ReadLimit
+	ErrWSMessageTooLarge = errors.New("ctx: websocket: message too large")
+	// ErrWSClosed 连接已经关闭或者正在关闭
+	ErrWSClosed = errors.New("ctx: websocket: connection closed")
+)
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketOptions WebSocket连接的配置，零值的字段使用默认值
+type WebSocketOptions struct {
+	// ReadLimit 单条消息(分片合并后)的最大字节数，<=0 时为1M，超过时以1009关闭连接
+	ReadLimit int64
+	// PongWait 多久没有收到客户端的任何帧就认为连接已经断开，<=0 时为60s
+	PongWait time.Duration
+	// PingInterval 发送ping的间隔，需要小于PongWait，<=0 时为PongWait的9/10
+	PingInterval time.Duration
+	// WriteWait 写一帧的超时时间，<=0 时为10s
+	WriteWait time.Duration
+	// WriteQueueSize 写队列的长度，队列满时 WriteMessage 阻塞，<=0 时为16
+	WriteQueueSize int
+	// CheckOrigin 检查 Origin，为nil时只允许没有 Origin 或者 Origin 和 Host 相同的请求
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols 服务端支持的子协议，按客户端的顺序选择第一个支持的
+	Subprotocols []string
+}
+
+func (o *WebSocketOptions) withDefaults() WebSocketOptions {
+	res := *o
+	if res.ReadLimit <= 0 {
+		res.ReadLimit = 1 << 20
+	}
+	if res.PongWait <= 0 {
+		res.PongWait = 60 * time.Second
+	}
+	if res.PingInterval <= 0 {
+		res.PingInterval = res.PongWait * 9 / 10
+	}
+	if res.WriteWait <= 0 {
+		res.WriteWait = 10 * time.Second
+	}
+	if res.WriteQueueSize <= 0 {
+		res.WriteQueueSize = 16
+	}
+	if res.CheckOrigin == nil {
+		res.CheckOrigin = sameOrigin
+	}
+	return res
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// CloseError 收到客户端的关闭帧时 ReadMessage 返回的错误
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("ctx: websocket: closed with %d %s", e.Code, e.Text)
+}
+
+// WebSocket 升级连接之后调用h，h返回时连接以1000关闭，握手失败时已经写回了错误响应
+//
+//	svr.Route(http.MethodGet, "/ws", ctx.WebSocket(ctx.WebSocketOptions{}, func(c *ctx.Context, ws *ctx.WSConn) {
+//		for {
+//			typ, msg, err := ws.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			ws.WriteMessage(typ, msg)
+//		}
+//	}))
+func WebSocket(opts WebSocketOptions, h func(c *Context, ws *WSConn)) HandleFunc {
+	return func(c *Context) {
+		ws, err := c.Upgrade(opts)
+		if err != nil {
+			return
+		}
+		defer ws.Close(CloseNormalClosure, "")
+		h(c, ws)
+	}
+}
+
+// WSConn 升级后的WebSocket连接
+// ReadMessage 只能在一个goroutine中调用，WriteMessage、Close 可以在多个goroutine中调用
+// 写入的消息先放到队列中，由单独的goroutine按顺序写出，同时定时发送ping
+type WSConn struct {
+	// Subprotocol 协商出的子协议，没有时为空
+	Subprotocol string
+
+	conn net.Conn
+	br   *bufio.Reader
+	opts WebSocketOptions
+
+	queue chan wsFrame
+	// closing 已经发出或者准备发出关闭帧，之后不能再写入消息
+	closing uint32
+	// done 底层连接关闭时关闭
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+type wsFrame struct {
+	op      byte
+	payload []byte
+}
+
+// Upgrade 完成WebSocket握手并接管连接，请求结束时连接会被关闭，所以处理完之前handler不能返回
+// 握手失败时写回400、403或者426并返回错误；服务开始关闭(见 Draining)时以1001关闭连接
+func (c *Context) Upgrade(opts WebSocketOptions) (*WSConn, error) {
+	opts = opts.withDefaults()
+	r := c.R
+	if r.Method != http.MethodGet ||
+		!headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") {
+		http.Error(c.W, "websocket: bad handshake", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.W.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(c.W, "websocket: unsupported version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+		http.Error(c.W, "websocket: bad handshake", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if !opts.CheckOrigin(r) {
+		http.Error(c.W, "websocket: origin not allowed", http.StatusForbidden)
+		return nil, ErrOriginNotAllowed
+	}
+
+	conn, brw, err := c.rw.Hijack()
+	if err != nil {
+		http.Error(c.W, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	ws := &WSConn{
+		Subprotocol: selectSubprotocol(r, opts.Subprotocols),
+		conn:        conn,
+		br:          brw.Reader,
+		opts:        opts,
+		queue:       make(chan wsFrame, opts.WriteQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if ws.Subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + ws.Subprotocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+	// 去掉http.Server设置的超时，之后由PongWait、WriteWait控制
+	conn.SetDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Now().Add(opts.WriteWait))
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(opts.PongWait))
+
+	c.ws = ws
+	go ws.writeLoop()
+	go func(draining <-chan struct{}) {
+		select {
+		case <-draining:
+			ws.Close(CloseGoingAway, "server shutdown")
+		case <-ws.done:
+		}
+	}(c.Draining())
+	return ws, nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContainsToken 检查逗号分隔的header中是否有token，不区分大小写
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			p = strings.TrimSpace(p)
+			for _, s := range supported {
+				if p == s {
+					return p
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Done 连接关闭时关闭
+func (ws *WSConn) Done() <-chan struct{} {
+	return ws.done
+}
+
+// WriteMessage 把消息放到写队列，队列满时阻塞，写出失败时连接会被关闭
+func (ws *WSConn) WriteMessage(typ int, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("ctx: websocket: invalid message type %d", typ)
+	}
+	if atomic.LoadUint32(&ws.closing) == 1 {
+		return ErrWSClosed
+	}
+	return ws.enqueue(wsFrame{op: byte(typ), payload: data})
+}
+
+// WriteJson 以JSON格式发送文本消息
+func (ws *WSConn) WriteJson(v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, buf)
+}
+
+// ReadJson 读取一条消息并按JSON解析
+func (ws *WSConn) ReadJson(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (ws *WSConn) enqueue(f wsFrame) error {
+	select {
+	case ws.queue <- f:
+		return nil
+	case <-ws.done:
+		return ErrWSClosed
+	}
+}
+
+// Close 发送关闭帧之后关闭连接，队列中已有的消息会先写出，重复调用直接返回
+func (ws *WSConn) Close(code int, text string) error {
+	if !atomic.CompareAndSwapUint32(&ws.closing, 0, 1) {
+		return nil
+	}
+	if err := ws.enqueue(wsFrame{op: CloseMessage, payload: closePayload(code, text)}); err != nil {
+		return err
+	}
+	<-ws.done
+	return nil
+}
+
+func closePayload(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return nil
+	}
+	// 控制帧的payload最多125字节
+	if len(text) > 123 {
+		text = text[:123]
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+func (ws *WSConn) shutdown() {
+	ws.doneOnce.Do(func() {
+		close(ws.done)
+		ws.conn.Close()
+	})
+}
+
+// writeLoop 唯一写连接的goroutine，写出关闭帧之后关闭连接
+func (ws *WSConn) writeLoop() {
+	ticker := time.NewTicker(ws.opts.PingInterval)
+	defer ticker.Stop()
+	defer ws.shutdown()
+	for {
+		select {
+		case f := <-ws.queue:
+			if err := ws.writeFrame(f.op, f.payload); err != nil || f.op == CloseMessage {
+				return
+			}
+		case <-ticker.C:
+			if err := ws.writeFrame(PingMessage, nil); err != nil {
+				return
+			}
+		case <-ws.done:
+			return
+		}
+	}
+}
+
+// writeFrame 服务端发送的帧不需要mask，消息不分片
+func (ws *WSConn) writeFrame(op byte, payload []byte) error {
+	var hdr [10]byte
+	hdr[0] = 0x80 | op
+	n := 2
+	switch l := len(payload); {
+	case l <= 125:
+		hdr[1] = byte(l)
+	case l <= 0xffff:
+		hdr[1] = 126
+		binary.BigEndian.PutUint16(hdr[2:], uint16(l))
+		n += 2
+	default:
+		hdr[1] = 127
+		binary.BigEndian.PutUint64(hdr[2:], uint64(l))
+		n += 8
+	}
+	ws.conn.SetWriteDeadline(time.Now().Add(ws.opts.WriteWait))
+	bufs := net.Buffers{hdr[:n], payload}
+	_, err := bufs.WriteTo(ws.conn)
+	return err
+}
+
+// ReadMessage 读取一条完整的消息，ping、pong以及分片由内部处理
+// 客户端关闭时返回 *CloseError，连接已经关闭时返回 ErrWSClosed
+func (ws *WSConn) ReadMessage() (int, []byte, error) {
+	var typ int
+	var msg []byte
+	for {
+		fin, op, payload, err := ws.readFrame(int64(len(msg)))
+		if err != nil {
+			return 0, nil, ws.readError(err)
+		}
+		// 收到任何帧都说明连接还活着
+		ws.conn.SetReadDeadline(time.Now().Add(ws.opts.PongWait))
+
+		switch op {
+		case PingMessage:
+			if atomic.LoadUint32(&ws.closing) == 0 {
+				ws.enqueue(wsFrame{op: PongMessage, payload: payload})
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			return 0, nil, ws.onClose(payload)
+		case TextMessage, BinaryMessage:
+			if typ != 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "unexpected data frame")
+			}
+			typ = int(op)
+			msg = payload
+		case continuationFrame:
+			if typ == 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+			msg = append(msg, payload...)
+		default:
+			return 0, nil, ws.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if !fin {
+			continue
+		}
+		if typ == TextMessage && !utf8.Valid(msg) {
+			return 0, nil, ws.fail(CloseInvalidPayload, "invalid utf8")
+		}
+		return typ, msg, nil
+	}
+}
+
+// wsProtocolError 需要以对应的状态码关闭连接的错误
+type wsProtocolError struct {
+	code int
+	text string
+}
+
+func (e *wsProtocolError) Error() string {
+	return "ctx: websocket: " + e.text
+}
+
+// readFrame read为当前消息已经读到的字节数，用来检查 ReadLimit
+func (ws *WSConn) readFrame(read int64) (bool, byte, []byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(ws.br, hdr[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := hdr[0]&0x80 != 0
+	op := hdr[0] & 0x0f
+	if hdr[0]&0x70 != 0 {
+		return false, 0, nil, &wsProtocolError{CloseProtocolError, "reserved bits set"}
+	}
+	// 客户端发送的帧必须mask
+	if hdr[1]&0x80 == 0 {
+		return false, 0, nil, &wsProtocolError{CloseProtocolError, "frame not masked"}
+	}
+
+	length := int64(hdr[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+		if length < 0 {
+			return false, 0, nil, &wsProtocolError{CloseProtocolError, "invalid length"}
+		}
+	}
+
+	// 和ReadLimit比较时用减法，客户端发送很大的length时相加会溢出
+	if op >= CloseMessage {
+		if !fin || length > 125 {
+			return false, 0, nil, &wsProtocolError{CloseProtocolError, "invalid control frame"}
+		}
+	} else if length > ws.opts.ReadLimit-read {
+		return false, 0, nil, &wsProtocolError{CloseMessageTooBig, "message too large"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i&3]
+	}
+	return fin, op, payload, nil
+}
+
+func (ws *WSConn) readError(err error) error {
+	var pe *wsProtocolError
+	if errors.As(err, &pe) {
+		return ws.fail(pe.code, pe.text)
+	}
+	// 本端关闭或者写出失败导致的读取错误
+	select {
+	case <-ws.done:
+		return ErrWSClosed
+	default:
+	}
+	ws.shutdown()
+	return err
+}
+
+// fail 以code关闭连接
+func (ws *WSConn) fail(code int, text string) error {
+	ws.Close(code, text)
+	if code == CloseMessageTooBig {
+		return ErrWSMessageTooLarge
+	}
+	return &wsProtocolError{code, text}
+}
+
+// onClose 收到关闭帧，本端还没有发送关闭帧的话回一个相同状态码的关闭帧
+func (ws *WSConn) onClose(payload []byte) error {
+	ce := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		ws.fail(CloseProtocolError, "invalid close payload")
+		return ce
+	case len(payload) >= 2:
+		ce.Code = int(binary.BigEndian.Uint16(payload))
+		ce.Text = string(payload[2:])
+		if !validCloseCode(ce.Code) || !utf8.ValidString(ce.Text) {
+			ws.fail(CloseProtocolError, "invalid close payload")
+			return ce
+		}
+	}
+	ws.Close(ce.Code, "")
+	// 本端先发送的关闭帧时Close直接返回，收到回复之后关闭连接
+	ws.shutdown()
+	return ce
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code >= 1000 && code <= 1011:
+		return code != 1004 && code != CloseNoStatusReceived && code != 1006
+	}
+	return false
+}
@@ -0,0 +1,358 @@
+package ctx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// clientFrame 构造客户端发送的帧，length<0 时按payload的长度编码
+func clientFrame(op byte, fin bool, payload []byte, length int64) []byte {
+	var b bytes.Buffer
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	b.WriteByte(b0)
+	if length < 0 {
+		length = int64(len(payload))
+	}
+	switch {
+	case length <= 125:
+		b.WriteByte(0x80 | byte(length))
+	case length <= 0xffff:
+		b.WriteByte(0x80 | 126)
+		binary.Write(&b, binary.BigEndian, uint16(length))
+	default:
+		b.WriteByte(0x80 | 127)
+		binary.Write(&b, binary.BigEndian, uint64(length))
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	b.Write(mask)
+	for i, c := range payload {
+		b.WriteByte(c ^ mask[i&3])
+	}
+	return b.Bytes()
+}
+
+func closeFramePayload(code int, text string) []byte {
+	buf := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	return append(buf, text...)
+}
+
+func TestWSReadFrame(t *testing.T) {
+	big := bytes.Repeat([]byte("a"), 300)
+	tests := []struct {
+		name    string
+		in      []byte
+		read    int64
+		wantFin bool
+		wantOp  byte
+		wantLen int
+		// wantCode 不为0时期望关闭连接的错误码
+		wantCode int
+	}{
+		{name: "short text", in: clientFrame(TextMessage, true, []byte("hello"), -1), wantFin: true, wantOp: TextMessage, wantLen: 5},
+		{name: "16 bit length", in: clientFrame(BinaryMessage, true, big, -1), wantFin: true, wantOp: BinaryMessage, wantLen: 300},
+		{name: "continuation not fin", in: clientFrame(continuationFrame, false, []byte("x"), -1), read: 1, wantOp: continuationFrame, wantLen: 1},
+		{name: "ping", in: clientFrame(PingMessage, true, []byte("p"), -1), wantFin: true, wantOp: PingMessage, wantLen: 1},
+		{name: "unmasked", in: []byte{0x81, 0x01, 'a'}, wantCode: CloseProtocolError},
+		{name: "reserved bits", in: append([]byte{0xc1}, clientFrame(TextMessage, true, []byte("a"), -1)[1:]...), wantCode: CloseProtocolError},
+		{name: "fragmented control frame", in: clientFrame(PingMessage, false, nil, -1), wantCode: CloseProtocolError},
+		{name: "control frame too long", in: clientFrame(PongMessage, true, big[:126], -1), wantCode: CloseProtocolError},
+		{name: "oversized frame", in: clientFrame(BinaryMessage, true, nil, 1025), wantCode: CloseMessageTooBig},
+		{name: "oversized with previous fragments", in: clientFrame(continuationFrame, true, nil, 25), read: 1000, wantCode: CloseMessageTooBig},
+		{name: "64 bit length overflow", in: clientFrame(continuationFrame, true, nil, 1<<63-1), read: 1, wantCode: CloseMessageTooBig},
+		{name: "negative length", in: []byte{0x82, 0xff, 0x80, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}, wantCode: CloseProtocolError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := &WSConn{
+				br:   bufio.NewReader(bytes.NewReader(tt.in)),
+				opts: WebSocketOptions{ReadLimit: 1024},
+			}
+			fin, op, payload, err := ws.readFrame(tt.read)
+			if tt.wantCode != 0 {
+				var pe *wsProtocolError
+				if !errors.As(err, &pe) || pe.code != tt.wantCode {
+					t.Fatalf("err = %v, want close code %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fin != tt.wantFin || op != tt.wantOp || len(payload) != tt.wantLen {
+				t.Errorf("got fin=%v op=%d len=%d, want fin=%v op=%d len=%d",
+					fin, op, len(payload), tt.wantFin, tt.wantOp, tt.wantLen)
+			}
+		})
+	}
+}
+
+type serverFrame struct {
+	op      byte
+	payload []byte
+}
+
+// newTestWSConn 用net.Pipe连接服务端的WSConn和模拟的客户端，客户端收到的帧放到返回的channel
+func newTestWSConn(t *testing.T) (*WSConn, net.Conn, <-chan serverFrame) {
+	server, client := net.Pipe()
+	ws := &WSConn{
+		conn: server,
+		br:   bufio.NewReader(server),
+		opts: (&WebSocketOptions{ReadLimit: 16, PongWait: 5 * time.Second}).withDefaults(),
+		done: make(chan struct{}),
+	}
+	ws.queue = make(chan wsFrame, ws.opts.WriteQueueSize)
+	go ws.writeLoop()
+
+	frames := make(chan serverFrame, 16)
+	go func() {
+		defer close(frames)
+		br := bufio.NewReader(client)
+		for {
+			var hdr [2]byte
+			if _, err := io.ReadFull(br, hdr[:]); err != nil {
+				return
+			}
+			// 服务端发送的帧不超过125字节，不需要处理扩展长度
+			payload := make([]byte, hdr[1]&0x7f)
+			if _, err := io.ReadFull(br, payload); err != nil {
+				return
+			}
+			frames <- serverFrame{op: hdr[0] & 0x0f, payload: payload}
+		}
+	}()
+	t.Cleanup(func() {
+		client.Close()
+		ws.shutdown()
+	})
+	return ws, client, frames
+}
+
+// waitClose 返回服务端发出的关闭帧
+func waitClose(t *testing.T, frames <-chan serverFrame) []byte {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case f, ok := <-frames:
+			if !ok {
+				t.Fatal("connection closed without close frame")
+			}
+			if f.op == CloseMessage {
+				return f.payload
+			}
+		case <-timeout:
+			t.Fatal("timeout waiting for close frame")
+		}
+	}
+}
+
+func TestWSReadMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		frames   [][]byte
+		wantType int
+		wantMsg  string
+		// wantClose 不为0时期望服务端发出的关闭帧的状态码，-1表示没有状态码
+		wantClose int
+		wantErr   error
+	}{
+		{
+			name: "fragmented text with ping",
+			frames: [][]byte{
+				clientFrame(TextMessage, false, []byte("hel"), -1),
+				clientFrame(PingMessage, true, []byte("p"), -1),
+				clientFrame(continuationFrame, true, []byte("lo"), -1),
+			},
+			wantType: TextMessage,
+			wantMsg:  "hello",
+		},
+		{
+			name:     "binary",
+			frames:   [][]byte{clientFrame(BinaryMessage, true, []byte{0xff, 0x00}, -1)},
+			wantType: BinaryMessage,
+			wantMsg:  "\xff\x00",
+		},
+		{
+			name:      "continuation without start",
+			frames:    [][]byte{clientFrame(continuationFrame, true, []byte("x"), -1)},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "data frame inside fragmented message",
+			frames: [][]byte{
+				clientFrame(TextMessage, false, []byte("a"), -1),
+				clientFrame(TextMessage, true, []byte("b"), -1),
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "unknown opcode",
+			frames:    [][]byte{clientFrame(3, true, nil, -1)},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "invalid utf8",
+			frames:    [][]byte{clientFrame(TextMessage, true, []byte{0xc3, 0x28}, -1)},
+			wantClose: CloseInvalidPayload,
+		},
+		{
+			name: "fragments exceed limit",
+			frames: [][]byte{
+				clientFrame(BinaryMessage, false, bytes.Repeat([]byte("a"), 10), -1),
+				clientFrame(continuationFrame, true, bytes.Repeat([]byte("a"), 10), -1),
+			},
+			wantClose: CloseMessageTooBig,
+			wantErr:   ErrWSMessageTooLarge,
+		},
+		{
+			name: "huge continuation length",
+			frames: [][]byte{
+				clientFrame(BinaryMessage, false, []byte("a"), -1),
+				clientFrame(continuationFrame, true, nil, 1<<63-1),
+			},
+			wantClose: CloseMessageTooBig,
+			wantErr:   ErrWSMessageTooLarge,
+		},
+		{
+			name:      "client close",
+			frames:    [][]byte{clientFrame(CloseMessage, true, closeFramePayload(CloseNormalClosure, "bye"), -1)},
+			wantClose: CloseNormalClosure,
+			wantErr:   &CloseError{Code: CloseNormalClosure, Text: "bye"},
+		},
+		{
+			name:      "client close without status",
+			frames:    [][]byte{clientFrame(CloseMessage, true, nil, -1)},
+			wantClose: -1,
+			wantErr:   &CloseError{Code: CloseNoStatusReceived},
+		},
+		{
+			name:      "close with reserved code",
+			frames:    [][]byte{clientFrame(CloseMessage, true, closeFramePayload(CloseNoStatusReceived, ""), -1)},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "close with one byte payload",
+			frames:    [][]byte{clientFrame(CloseMessage, true, []byte{0x03}, -1)},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name:      "close with application code",
+			frames:    [][]byte{clientFrame(CloseMessage, true, closeFramePayload(4000, ""), -1)},
+			wantClose: 4000,
+			wantErr:   &CloseError{Code: 4000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, client, frames := newTestWSConn(t)
+			go func() {
+				for _, f := range tt.frames {
+					if _, err := client.Write(f); err != nil {
+						return
+					}
+				}
+			}()
+
+			typ, msg, err := ws.ReadMessage()
+			if tt.wantClose == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if typ != tt.wantType || string(msg) != tt.wantMsg {
+					t.Errorf("got %d %q, want %d %q", typ, msg, tt.wantType, tt.wantMsg)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("got message %d %q, want error", typ, msg)
+			}
+			var ce *CloseError
+			switch want := tt.wantErr.(type) {
+			case nil:
+			case *CloseError:
+				if !errors.As(err, &ce) || *ce != *want {
+					t.Errorf("err = %v, want %v", err, want)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("err = %v, want %v", err, want)
+				}
+			}
+
+			payload := waitClose(t, frames)
+			if tt.wantClose == -1 {
+				if len(payload) != 0 {
+					t.Errorf("close payload = %v, want empty", payload)
+				}
+				return
+			}
+			if len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != tt.wantClose {
+				t.Errorf("close payload = %v, want code %d", payload, tt.wantClose)
+			}
+		})
+	}
+}
+
+func TestWSPingReply(t *testing.T) {
+	ws, client, frames := newTestWSConn(t)
+	go func() {
+		client.Write(clientFrame(PingMessage, true, []byte("hi"), -1))
+		client.Write(clientFrame(TextMessage, true, []byte("x"), -1))
+	}()
+	go ws.ReadMessage()
+
+	select {
+	case f := <-frames:
+		if f.op != PongMessage || string(f.payload) != "hi" {
+			t.Errorf("got op=%d %q, want pong \"hi\"", f.op, f.payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for pong")
+	}
+}
+
+func TestWSWriteFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantHdr []byte
+	}{
+		{name: "7 bit", size: 125, wantHdr: []byte{0x82, 125}},
+		{name: "16 bit", size: 126, wantHdr: []byte{0x82, 126, 0x00, 126}},
+		{name: "64 bit", size: 1 << 16, wantHdr: []byte{0x82, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			ws := &WSConn{conn: server, opts: (&WebSocketOptions{}).withDefaults()}
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- ws.writeFrame(BinaryMessage, make([]byte, tt.size))
+			}()
+			got := make([]byte, len(tt.wantHdr)+tt.size)
+			if _, err := io.ReadFull(client, got); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-errCh; err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got[:len(tt.wantHdr)], tt.wantHdr) {
+				t.Errorf("header = %v, want %v", got[:len(tt.wantHdr)], tt.wantHdr)
+			}
+		})
+	}
+}
@@ -1,27 +1,27 @@
 package dto
 
 type MQPushReq struct {
-	ExchangeName string `json:"exchange_name"`
-	RoutingKey   string `json:"routing_key"`
-	Body         string `json:"body"`
+	ExchangeName string `json:"exchange_name" validate:"required,max=255"`
+	RoutingKey   string `json:"routing_key" validate:"max=255"`
+	Body         string `json:"body" validate:"required"`
 }
 
 type MQCreateExchangeReq struct {
-	ExchangeName string `json:"exchange_name"`
-	ExchangeType string `json:"exchange_type"`
+	ExchangeName string `json:"exchange_name" validate:"required,max=255"`
+	ExchangeType string `json:"exchange_type" validate:"required,oneof=direct fanout topic headers"`
 }
 
 type MQQueueBindReq struct {
-	QueueName    string `json:"queue_name"`
-	BindingKey   string `json:"binding_key"`
-	ExchangeName string `json:"exchange_name"`
+	QueueName    string `json:"queue_name" validate:"required,max=255"`
+	BindingKey   string `json:"binding_key" validate:"max=255"`
+	ExchangeName string `json:"exchange_name" validate:"required,max=255"`
 }
 
 type KafkaMsg struct {
 	Key   string `json:"key"`
-	Value string `json:"value"`
+	Value string `json:"value" validate:"required"`
 }
 type KafkaPublishReq struct {
-	Topic string     `json:"topic"`
-	Msgs  []KafkaMsg `json:"msgs"`
+	Topic string     `json:"topic" validate:"required,max=249,regex=^[a-zA-Z0-9._-]+$"`
+	Msgs  []KafkaMsg `json:"msgs" validate:"required,min=1"`
 }
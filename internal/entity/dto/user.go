@@ -1,6 +1,6 @@
 package dto
 
 type User struct {
-	Name string `json:"name"`
-	Age  int    `json:"age"`
+	Name string `json:"name" validate:"required,max=32"`
+	Age  int    `json:"age" validate:"min=0,max=150"`
 }
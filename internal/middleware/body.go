@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"myserver/internal/ctx"
+)
+
+// Body 设置读取请求body的配置，超过大小时读取body返回 ctx.ErrBodyTooLarge
+// 作为全局中间件时是整个server的配置，放在路由或者分组上可以覆盖全局的配置
+//
+//	g.Route(http.MethodPost, "/upload", middleware.Body(ctx.BodyOptions{MaxBytes: 64 << 20}), upload)
+func Body(opts ctx.BodyOptions) ctx.HandleFunc {
+	return func(c *ctx.Context) {
+		c.SetBodyOptions(opts)
+	}
+}
+
+// BodyLimit 只修改body的最大字节数，其余配置保持不变
+func BodyLimit(n int64) ctx.HandleFunc {
+	return func(c *ctx.Context) {
+		opts := c.BodyOptions()
+		opts.MaxBytes = n
+		c.SetBodyOptions(opts)
+	}
+}
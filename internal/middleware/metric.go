@@ -20,6 +20,7 @@ func Metric() ctx.HandleFunc {
 		start := time.Now().UnixNano() * 1e3
 		c.Next()
 		end := time.Now().UnixNano() * 1e3
-		log.Printf("[%s] [COST] %d us\n", uuidStr, end-start)
+		rsp := c.Response()
+		log.Printf("[%s] [COST] %d us, status:%d, size:%d\n", uuidStr, end-start, rsp.Status(), rsp.Size())
 	}
 }
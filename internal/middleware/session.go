@@ -0,0 +1,302 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"myserver/internal/ctx"
+	"sync"
+	"time"
+)
+
+const sessionKey = "middleware/session"
+
+// 浏览器对单个cookie的限制是4096字节，包括名字和属性
+const maxSessionCookieSize = 4000
+
+// SessionOptions session中间件的配置
+type SessionOptions struct {
+	// Name cookie的名字，为空时是 session
+	Name string
+	// Keys 签名或者加密用的key，每个不少于32字节
+	// 第一个用来签名，其余的只用来验证，轮换时把新key放到最前面，旧key保留到旧cookie都过期
+	Keys [][]byte
+	// Encrypt 使用AES-GCM加密cookie，否则只用HMAC签名，内容客户端可以看到
+	Encrypt bool
+	// Store 为nil时session保存在cookie中，否则cookie中只保存session id
+	Store SessionStore
+	// IdleTimeout 多久没有访问就过期，<=0 时为30分钟
+	IdleTimeout time.Duration
+	// AbsoluteTimeout 创建之后最多有效多久，<=0 时为24小时
+	AbsoluteTimeout time.Duration
+	// Cookie cookie的Path、Domain等，MaxAge 按过期时间自动设置
+	Cookie ctx.CookieOptions
+}
+
+// Session 一个请求的session，可以在多个goroutine中使用
+// 值会编码成JSON保存，取出来时数字是float64，结构体是map
+type Session struct {
+	mu       sync.Mutex
+	id       string
+	values   map[string]interface{}
+	created  time.Time
+	accessed time.Time
+
+	isNew     bool
+	changed   bool
+	destroyed bool
+	// Renew 之前的id，保存时从store中删除
+	oldID string
+}
+
+type sessionRecord struct {
+	ID       string                 `json:"id"`
+	Values   map[string]interface{} `json:"v"`
+	Created  int64                  `json:"c"`
+	Accessed int64                  `json:"a"`
+}
+
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// IsNew 是否是这次请求新建的session
+func (s *Session) IsNew() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isNew
+}
+
+func (s *Session) Get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// GetString 不存在或者类型不对时返回空
+func (s *Session) GetString(key string) string {
+	v, _ := s.Get(key)
+	str, _ := v.(string)
+	return str
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.changed = true
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	s.changed = true
+}
+
+// Clear 删除所有的值，session本身仍然有效
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]interface{})
+	s.changed = true
+}
+
+// Renew 换一个新的id并且重新计算过期时间，值保持不变，登录等权限变化之后调用，防止session固定攻击
+func (s *Session) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oldID == "" && !s.isNew {
+		s.oldID = s.id
+	}
+	s.id = newSessionID()
+	s.created = time.Now()
+	s.changed = true
+}
+
+// Destroy 删除session以及cookie，退出登录时调用
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]interface{})
+	s.destroyed = true
+}
+
+// GetSession 返回当前请求的session，没有使用 Sessions 中间件时panic
+func GetSession(c *ctx.Context) *Session {
+	return c.MustGet(sessionKey).(*Session)
+}
+
+// Sessions session中间件，请求开始时从cookie(以及store)中加载session，发送响应头之前保存
+// 每次请求都会刷新空闲过期时间，所以有值的session每次都会重新设置cookie
+//
+//	h, err := server.NewHandler(router, middleware.Sessions(middleware.SessionOptions{
+//		Keys:  [][]byte{key},
+//		Store: middleware.NewMemorySessionStore(),
+//	}))
+//	if err != nil {
+//		log.Fatalf("failed to create handler, err:%v\n", err)
+//	}
+//	svr := server.NewServerWithHandler(h)
+//	...
+//	middleware.GetSession(c).Set("uid", user.Id)
+func Sessions(opts SessionOptions) ctx.HandleFunc {
+	if opts.Name == "" {
+		opts.Name = "session"
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 30 * time.Minute
+	}
+	if opts.AbsoluteTimeout <= 0 {
+		opts.AbsoluteTimeout = 24 * time.Hour
+	}
+	codec := newSessionCodec(opts.Name, opts.Keys, opts.Encrypt)
+
+	return func(c *ctx.Context) {
+		s := loadSession(c, &opts, codec)
+		c.Set(sessionKey, s)
+
+		var once sync.Once
+		save := func() {
+			once.Do(func() {
+				if err := saveSession(c, s, &opts, codec); err != nil {
+					log.Printf("session save failed: %v\n", err)
+				}
+			})
+		}
+		c.BeforeWrite(save)
+		c.Next()
+		// handler没有写响应时由net/http写200，不会经过BeforeWrite
+		if !c.Response().Written() {
+			save()
+		}
+	}
+}
+
+func loadSession(c *ctx.Context, opts *SessionOptions, codec *sessionCodec) *Session {
+	raw, err := c.Cookie(opts.Name)
+	if err != nil {
+		return newSession()
+	}
+	data, rotated, err := codec.decode(raw)
+	if err != nil {
+		return newSession()
+	}
+	if opts.Store != nil {
+		var ok bool
+		data, ok, err = opts.Store.Load(string(data))
+		if err != nil {
+			log.Printf("session load failed: %v\n", err)
+		}
+		if !ok {
+			return newSession()
+		}
+	}
+
+	var rec sessionRecord
+	if err := json.Unmarshal(data, &rec); err != nil || rec.ID == "" {
+		return newSession()
+	}
+	now := time.Now()
+	created, accessed := time.Unix(rec.Created, 0), time.Unix(rec.Accessed, 0)
+	if now.Sub(created) > opts.AbsoluteTimeout || now.Sub(accessed) > opts.IdleTimeout {
+		if opts.Store != nil {
+			opts.Store.Delete(rec.ID)
+		}
+		return newSession()
+	}
+	if rec.Values == nil {
+		rec.Values = make(map[string]interface{})
+	}
+	return &Session{
+		id:       rec.ID,
+		values:   rec.Values,
+		created:  created,
+		accessed: accessed,
+		changed:  rotated,
+	}
+}
+
+func saveSession(c *ctx.Context, s *Session, opts *SessionOptions, codec *sessionCodec) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opts.Store != nil && s.oldID != "" {
+		if err := opts.Store.Delete(s.oldID); err != nil {
+			return err
+		}
+		s.oldID = ""
+	}
+	if s.destroyed {
+		if s.isNew {
+			return nil
+		}
+		c.DeleteCookie(opts.Name, opts.Cookie)
+		if opts.Store != nil {
+			return opts.Store.Delete(s.id)
+		}
+		return nil
+	}
+	// 没有用到的新session不设置cookie
+	if s.isNew && !s.changed {
+		return nil
+	}
+
+	now := time.Now()
+	s.accessed = now
+	expiry := s.created.Add(opts.AbsoluteTimeout)
+	if idle := now.Add(opts.IdleTimeout); idle.Before(expiry) {
+		expiry = idle
+	}
+	data, err := json.Marshal(sessionRecord{
+		ID:       s.id,
+		Values:   s.values,
+		Created:  s.created.Unix(),
+		Accessed: s.accessed.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	if opts.Store != nil {
+		if err := opts.Store.Save(s.id, data, expiry); err != nil {
+			return err
+		}
+		data = []byte(s.id)
+	}
+
+	value, err := codec.encode(data)
+	if err != nil {
+		return err
+	}
+	if len(value) > maxSessionCookieSize {
+		return errSessionTooLarge
+	}
+	cookieOpts := opts.Cookie
+	cookieOpts.MaxAge = expiry.Sub(now)
+	c.SetCookie(opts.Name, value, cookieOpts)
+	return nil
+}
+
+func newSession() *Session {
+	now := time.Now()
+	return &Session{
+		id:       newSessionID(),
+		values:   make(map[string]interface{}),
+		created:  now,
+		accessed: now,
+		isNew:    true,
+	}
+}
+
+// newSessionID 32字节的随机数
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
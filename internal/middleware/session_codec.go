@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var (
+	errInvalidSessionCookie = errors.New("session: invalid cookie")
+	errSessionTooLarge      = errors.New("session: cookie too large, use a SessionStore")
+)
+
+// sessionCodec 对cookie的内容签名或者加密，第一个key用来编码，所有的key都可以解码，用于轮换key
+// cookie名字参与签名(加密时作为附加数据)，防止把一个cookie的值换到另一个cookie上
+type sessionCodec struct {
+	name  string
+	keys  [][]byte
+	aeads []cipher.AEAD
+}
+
+// newSessionCodec key不能少于32字节，加密时使用前32字节做AES-256-GCM
+func newSessionCodec(name string, keys [][]byte, encrypt bool) *sessionCodec {
+	if len(keys) == 0 {
+		panic("session: at least one key is required")
+	}
+	sc := &sessionCodec{name: name, keys: keys}
+	for _, k := range keys {
+		if len(k) < 32 {
+			panic("session: key must be at least 32 bytes")
+		}
+		if !encrypt {
+			continue
+		}
+		block, err := aes.NewCipher(k[:32])
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		sc.aeads = append(sc.aeads, aead)
+	}
+	return sc
+}
+
+func (sc *sessionCodec) encode(data []byte) (string, error) {
+	if sc.aeads != nil {
+		aead := sc.aeads[0]
+		nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, data, []byte(sc.name))), nil
+	}
+	enc := base64.RawURLEncoding.EncodeToString(data)
+	return enc + "." + base64.RawURLEncoding.EncodeToString(sc.mac(sc.keys[0], enc)), nil
+}
+
+// decode rotated表示不是用第一个key编码的，需要重新编码
+func (sc *sessionCodec) decode(s string) (data []byte, rotated bool, err error) {
+	if sc.aeads != nil {
+		buf, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, false, errInvalidSessionCookie
+		}
+		for i, aead := range sc.aeads {
+			if len(buf) < aead.NonceSize() {
+				break
+			}
+			n := aead.NonceSize()
+			if data, err := aead.Open(nil, buf[:n], buf[n:], []byte(sc.name)); err == nil {
+				return data, i > 0, nil
+			}
+		}
+		return nil, false, errInvalidSessionCookie
+	}
+
+	idx := strings.LastIndexByte(s, '.')
+	if idx < 0 {
+		return nil, false, errInvalidSessionCookie
+	}
+	enc := s[:idx]
+	sig, err := base64.RawURLEncoding.DecodeString(s[idx+1:])
+	if err != nil {
+		return nil, false, errInvalidSessionCookie
+	}
+	for i, k := range sc.keys {
+		if !hmac.Equal(sig, sc.mac(k, enc)) {
+			continue
+		}
+		data, err := base64.RawURLEncoding.DecodeString(enc)
+		if err != nil {
+			return nil, false, errInvalidSessionCookie
+		}
+		return data, i > 0, nil
+	}
+	return nil, false, errInvalidSessionCookie
+}
+
+func (sc *sessionCodec) mac(key []byte, enc string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(sc.name))
+	h.Write([]byte{'|'})
+	h.Write([]byte(enc))
+	return h.Sum(nil)
+}
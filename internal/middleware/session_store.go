@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// SessionStore 服务端保存session的存储，设置之后cookie中只保存签名(或加密)后的session id
+// data是编码后的session，expiry之后可以删除
+type SessionStore interface {
+	// Load 不存在或者已经过期时返回false
+	Load(id string) (data []byte, ok bool, err error)
+	Save(id string, data []byte, expiry time.Time) error
+	Delete(id string) error
+}
+
+// MemorySessionStore 保存在内存中的 SessionStore，只适合单实例部署
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	// 上次清理过期session的时间
+	lastGC time.Time
+}
+
+type memorySession struct {
+	data   []byte
+	expiry time.Time
+}
+
+var _ SessionStore = &MemorySessionStore{}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]memorySession),
+		lastGC:   time.Now(),
+	}
+}
+
+func (m *MemorySessionStore) Load(id string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(s.expiry) {
+		delete(m.sessions, id)
+		return nil, false, nil
+	}
+	return s.data, true, nil
+}
+
+// Save 每分钟最多顺便清理一次过期的session，不需要单独的goroutine
+func (m *MemorySessionStore) Save(id string, data []byte, expiry time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.lastGC) > time.Minute {
+		for k, s := range m.sessions {
+			if now.After(s.expiry) {
+				delete(m.sessions, k)
+			}
+		}
+		m.lastGC = now
+	}
+	m.sessions[id] = memorySession{data: data, expiry: expiry}
+	return nil
+}
+
+func (m *MemorySessionStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"myserver/internal/ctx"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var (
+	testKey1 = bytes.Repeat([]byte("1"), 32)
+	testKey2 = bytes.Repeat([]byte("2"), 32)
+)
+
+func TestSessionCodec(t *testing.T) {
+	tamper := func(s string) string {
+		b := []byte(s)
+		i := len(b) / 2
+		if b[i] == 'A' {
+			b[i] = 'B'
+		} else {
+			b[i] = 'A'
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name string
+		// 编码和解码用的cookie名字以及key
+		encName, decName string
+		encKeys, decKeys [][]byte
+		// modify 修改编码之后的cookie
+		modify      func(string) string
+		wantErr     bool
+		wantRotated bool
+	}{
+		{name: "round trip", encKeys: [][]byte{testKey1}, decKeys: [][]byte{testKey1}},
+		{name: "tampered", encKeys: [][]byte{testKey1}, decKeys: [][]byte{testKey1}, modify: tamper, wantErr: true},
+		{name: "truncated", encKeys: [][]byte{testKey1}, decKeys: [][]byte{testKey1},
+			modify: func(s string) string { return s[:len(s)-4] }, wantErr: true},
+		{name: "garbage", encKeys: [][]byte{testKey1}, decKeys: [][]byte{testKey1},
+			modify: func(string) string { return "not a cookie" }, wantErr: true},
+		{name: "unknown key", encKeys: [][]byte{testKey1}, decKeys: [][]byte{testKey2}, wantErr: true},
+		{name: "rotated key", encKeys: [][]byte{testKey1}, decKeys: [][]byte{testKey2, testKey1}, wantRotated: true},
+		{name: "other cookie name", encName: "other", encKeys: [][]byte{testKey1}, decKeys: [][]byte{testKey1}, wantErr: true},
+	}
+	for _, encrypt := range []bool{false, true} {
+		for _, tt := range tests {
+			name := tt.name + "/hmac"
+			if encrypt {
+				name = tt.name + "/aes-gcm"
+			}
+			t.Run(name, func(t *testing.T) {
+				encName, decName := "session", "session"
+				if tt.encName != "" {
+					encName = tt.encName
+				}
+				data := []byte(`{"id":"abc","v":{"uid":7}}`)
+				s, err := newSessionCodec(encName, tt.encKeys, encrypt).encode(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if encrypt && bytes.Contains([]byte(s), []byte("uid")) {
+					t.Errorf("encrypted cookie contains plaintext: %s", s)
+				}
+				if tt.modify != nil {
+					s = tt.modify(s)
+				}
+
+				got, rotated, err := newSessionCodec(decName, tt.decKeys, encrypt).decode(s)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatalf("decode succeeded, want error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("data = %s, want %s", got, data)
+				}
+				if rotated != tt.wantRotated {
+					t.Errorf("rotated = %v, want %v", rotated, tt.wantRotated)
+				}
+			})
+		}
+	}
+}
+
+func TestSessionCodecShortKey(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("short key did not panic")
+		}
+	}()
+	newSessionCodec("session", [][]byte{[]byte("short")}, false)
+}
+
+// sessionRequest 带上cookies执行一次请求，返回响应中的cookie
+func sessionRequest(h http.Handler, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestSessions(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		store SessionStore
+	}{
+		{name: "cookie"},
+		{name: "store", store: NewMemorySessionStore()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := SessionOptions{Keys: [][]byte{testKey1}, Store: tc.store}
+			h := ctx.ToHandler(Sessions(opts), func(c *ctx.Context) {
+				s := GetSession(c)
+				switch c.R.URL.Path {
+				case "/login":
+					s.Set("uid", "u1")
+					s.Renew()
+				case "/logout":
+					s.Destroy()
+				}
+				c.W.Write([]byte(s.GetString("uid")))
+			})
+
+			w := sessionRequest(h, "/", nil)
+			if len(w.Result().Cookies()) != 0 {
+				t.Errorf("unused session set a cookie")
+			}
+
+			w = sessionRequest(h, "/login", nil)
+			login := w.Result().Cookies()
+			if len(login) != 1 || !login[0].HttpOnly || login[0].MaxAge <= 0 {
+				t.Fatalf("login cookies = %v", login)
+			}
+			if tc.store != nil && bytes.Contains([]byte(login[0].Value), []byte("u1")) {
+				t.Errorf("store mode cookie contains session values")
+			}
+
+			if got := sessionRequest(h, "/", login).Body.String(); got != "u1" {
+				t.Errorf("uid = %q, want u1", got)
+			}
+
+			// 新key放在最前面之后旧cookie仍然有效，并且会用新key重新签名
+			rotated := ctx.ToHandler(Sessions(SessionOptions{Keys: [][]byte{testKey2, testKey1}, Store: tc.store}), func(c *ctx.Context) {
+				c.W.Write([]byte(GetSession(c).GetString("uid")))
+			})
+			w = sessionRequest(rotated, "/", login)
+			if w.Body.String() != "u1" {
+				t.Errorf("rotated uid = %q, want u1", w.Body.String())
+			}
+			newOnly := ctx.ToHandler(Sessions(SessionOptions{Keys: [][]byte{testKey2}, Store: tc.store}), func(c *ctx.Context) {
+				c.W.Write([]byte(GetSession(c).GetString("uid")))
+			})
+			if got := sessionRequest(newOnly, "/", w.Result().Cookies()).Body.String(); got != "u1" {
+				t.Errorf("re-signed cookie uid = %q, want u1", got)
+			}
+			if got := sessionRequest(newOnly, "/", login).Body.String(); got != "" {
+				t.Errorf("old key cookie accepted after removal, uid = %q", got)
+			}
+
+			w = sessionRequest(h, "/logout", login)
+			if cks := w.Result().Cookies(); len(cks) != 1 || cks[0].MaxAge >= 0 {
+				t.Errorf("logout cookies = %v, want deletion", cks)
+			}
+			if tc.store != nil {
+				if got := sessionRequest(h, "/", login).Body.String(); got != "" {
+					t.Errorf("destroyed session still valid, uid = %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		created  time.Time
+		accessed time.Time
+		wantUID  string
+	}{
+		{name: "active", created: now.Add(-time.Hour), accessed: now.Add(-time.Minute), wantUID: "u1"},
+		{name: "idle", created: now.Add(-time.Hour), accessed: now.Add(-31 * time.Minute)},
+		{name: "absolute", created: now.Add(-25 * time.Hour), accessed: now.Add(-time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := SessionOptions{Keys: [][]byte{testKey1}, IdleTimeout: 30 * time.Minute, AbsoluteTimeout: 24 * time.Hour}
+			h := ctx.ToHandler(Sessions(opts), func(c *ctx.Context) {
+				c.W.Write([]byte(GetSession(c).GetString("uid")))
+			})
+
+			data, _ := json.Marshal(sessionRecord{
+				ID:       "id",
+				Values:   map[string]interface{}{"uid": "u1"},
+				Created:  tt.created.Unix(),
+				Accessed: tt.accessed.Unix(),
+			})
+			value, err := newSessionCodec("session", opts.Keys, false).encode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := sessionRequest(h, "/", []*http.Cookie{{Name: "session", Value: value}}).Body.String()
+			if got != tt.wantUID {
+				t.Errorf("uid = %q, want %q", got, tt.wantUID)
+			}
+		})
+	}
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	m := NewMemorySessionStore()
+	m.Save("live", []byte("a"), time.Now().Add(time.Hour))
+	m.Save("expired", []byte("b"), time.Now().Add(-time.Second))
+
+	if data, ok, _ := m.Load("live"); !ok || string(data) != "a" {
+		t.Errorf("Load(live) = %q %v", data, ok)
+	}
+	if _, ok, _ := m.Load("expired"); ok {
+		t.Errorf("Load(expired) found an expired session")
+	}
+	m.Delete("live")
+	if _, ok, _ := m.Load("live"); ok {
+		t.Errorf("Load(live) found a deleted session")
+	}
+}
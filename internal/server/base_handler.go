@@ -0,0 +1,267 @@
+package server
+
+import (
+	"fmt"
+	"io/fs"
+	"myserver/internal/ctx"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// baseHandler 各种Handler实现共用的部分：全局中间件以及404/405的处理
+// 404/405 同样会经过全局中间件，保证metric、日志等中间件对所有请求生效
+type baseHandler struct {
+	// 具体的Handler，Group等方法创建的分组最终通过它注册路由
+	self Routable
+
+	globalMiddlewares []ctx.HandleFunc
+	// 注册过的所有method，405时用来计算 Allow
+	methods map[string]struct{}
+	// 按注册顺序记录的路由
+	routes []*RouteInfo
+	// 路由名字 -> 路由
+	names map[string]*RouteInfo
+
+	notFound         ctx.HandleFunc
+	methodNotAllowed ctx.HandleFunc
+
+	pathOpts PathOptions
+}
+
+func newBaseHandler(middlewares []ctx.HandleFunc) baseHandler {
+	wares := make([]ctx.HandleFunc, 0, len(middlewares))
+	wares = append(wares, middlewares...)
+	return baseHandler{
+		globalMiddlewares: wares,
+		methods:           make(map[string]struct{}),
+		names:             make(map[string]*RouteInfo),
+		notFound:          defaultNotFound,
+		methodNotAllowed:  defaultMethodNotAllowed,
+	}
+}
+
+// NotFound 设置路由不存在时的处理函数
+func (b *baseHandler) NotFound(h ctx.HandleFunc) {
+	b.notFound = h
+}
+
+// MethodNotAllowed 设置路径存在但method不匹配时的处理函数，执行时 Allow 头已经设置好了
+func (b *baseHandler) MethodNotAllowed(h ctx.HandleFunc) {
+	b.methodNotAllowed = h
+}
+
+func (b *baseHandler) Group(prefix string, middlewares ...ctx.HandleFunc) Routable {
+	return newRouteGroup(b.self, prefix, middlewares)
+}
+
+func (b *baseHandler) Host(pattern string) Routable {
+	return newConditionGroup(b.self, hostCondition(pattern))
+}
+
+func (b *baseHandler) Header(key, value string) Routable {
+	return newConditionGroup(b.self, headerCondition(key, value))
+}
+
+func (b *baseHandler) Mount(prefix string, h http.Handler) {
+	mount(b.self, prefix, h)
+}
+
+func (b *baseHandler) Static(prefix string, fsys fs.FS, opts StaticOptions) {
+	static(b.self, prefix, fsys, opts)
+}
+
+// routeFinder 路由查找，由具体的Handler实现
+// Host等条件在匹配method之前检查，条件不满足的路由相当于不存在
+type routeFinder interface {
+	// find 查找method+path对应并且条件满足r的路由，找不到返回nil
+	find(r *http.Request, method, path string) (*Route, ctx.Params)
+	// allowed 返回path上条件满足r的所有method，路径不存在时返回空
+	allowed(r *http.Request, path string) []string
+}
+
+// dispatch 规范化路径后查找路由并执行，处理HEAD、OPTIONS以及404/405
+func (b *baseHandler) dispatch(w http.ResponseWriter, r *http.Request, f routeFinder) {
+	p := r.URL.Path
+	if b.pathOpts.CleanPath || b.pathOpts.RedirectCleanPath {
+		if cp := cleanPath(p); cp != p {
+			if b.pathOpts.RedirectCleanPath {
+				b.serve(w, r, nil, b.chain([]ctx.HandleFunc{redirect(cp)}))
+				return
+			}
+			p = cp
+		}
+	}
+
+	route, params := f.find(r, r.Method, p)
+	// HEAD 没有单独注册的话，使用GET的handler处理，但是不返回body
+	if route == nil && r.Method == http.MethodHead {
+		route, params = f.find(r, http.MethodGet, p)
+		w = &headResponseWriter{ResponseWriter: w}
+	}
+
+	if route == nil {
+		// 路径存在但是method不匹配的话返回405
+		if allowed := f.allowed(r, p); len(allowed) > 0 {
+			b.serveMethodNotAllowed(w, r, allowed)
+			return
+		}
+		b.serveNotFound(w, r)
+		return
+	}
+
+	if b.pathOpts.RedirectTrailingSlash {
+		if target := trailingSlashTarget(route, p); target != "" {
+			b.serve(w, r, nil, b.chain([]ctx.HandleFunc{redirect(target)}))
+			return
+		}
+	}
+
+	b.serve(w, r, params, route.chain)
+}
+
+// register 记录路由信息并拼好完整的调用链
+func (b *baseHandler) register(spec *routeSpec) *Route {
+	b.methods[spec.method] = struct{}{}
+
+	names := make([]string, 0, len(spec.handlers))
+	for _, f := range spec.handlers {
+		names = append(names, funcName(f))
+	}
+	conds := make([]string, 0, len(spec.conditions))
+	for _, c := range spec.conditions {
+		conds = append(conds, c.desc)
+	}
+	info := &RouteInfo{
+		Method:      spec.method,
+		Pattern:     spec.path,
+		Handlers:    names,
+		Middlewares: len(b.globalMiddlewares) + len(spec.middlewares),
+		Conditions:  conds,
+	}
+	b.routes = append(b.routes, info)
+	return &Route{
+		info:       info,
+		chain:      b.chain(spec.funcs()),
+		base:       b,
+		conditions: spec.conditions,
+		condKey:    conditionKey(spec.conditions),
+	}
+}
+
+// Routes 按注册顺序返回所有路由
+func (b *baseHandler) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(b.routes))
+	for _, r := range b.routes {
+		routes = append(routes, *r)
+	}
+	return routes
+}
+
+func (b *baseHandler) setName(info *RouteInfo, name string) {
+	if old, ok := b.names[name]; ok && old != info {
+		panic(fmt.Sprintf("duplicate route name %s, method:%s, path:%s", name, info.Method, info.Pattern))
+	}
+	if info.Name != "" {
+		delete(b.names, info.Name)
+	}
+	info.Name = name
+	b.names[name] = info
+}
+
+// URLFor 根据路由名字生成路径，params 为 key, value 交替的列表，通配符的key为 "*"
+//
+//	URLFor("user.detail", "id", "12") // /user/12
+func (b *baseHandler) URLFor(name string, params ...string) (string, error) {
+	info, ok := b.names[name]
+	if !ok {
+		return "", fmt.Errorf("server: unknown route name %s", name)
+	}
+	return buildURL(info.Pattern, params...)
+}
+
+// allowedMethods 用match检查每个注册过的method，返回排好序的 Allow 列表
+// 注册了GET的话会自动加上HEAD，路径存在的话会自动加上OPTIONS
+func (b *baseHandler) allowedMethods(match func(method string) bool) []string {
+	allowed := make([]string, 0, 4)
+	for m := range b.methods {
+		if match(m) {
+			allowed = append(allowed, m)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	has := func(m string) bool {
+		for _, a := range allowed {
+			if a == m {
+				return true
+			}
+		}
+		return false
+	}
+	if has(http.MethodGet) && !has(http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if !has(http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// chain 把全局中间件和路由自己的handlers拼成完整的调用链
+// 注册路由时就拼好，避免每个请求都重新分配
+func (b *baseHandler) chain(handlers []ctx.HandleFunc) []ctx.HandleFunc {
+	hs := make([]ctx.HandleFunc, 0, len(b.globalMiddlewares)+len(handlers))
+	hs = append(hs, b.globalMiddlewares...)
+	hs = append(hs, handlers...)
+	return hs
+}
+
+// serve 执行完整的调用链
+func (b *baseHandler) serve(w http.ResponseWriter, r *http.Request, params ctx.Params, chain []ctx.HandleFunc) {
+	c := ctx.NewContext(w, r)
+	c.Params = params
+	c.Hs = chain
+	defer c.Finish()
+	c.Next()
+}
+
+func (b *baseHandler) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	b.serve(w, r, nil, b.chain([]ctx.HandleFunc{b.notFound}))
+}
+
+// serveMethodNotAllowed OPTIONS请求自动应答204，其余返回405
+func (b *baseHandler) serveMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed []string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	if r.Method == http.MethodOptions {
+		b.serve(w, r, nil, b.chain([]ctx.HandleFunc{defaultOptions}))
+		return
+	}
+	b.serve(w, r, nil, b.chain([]ctx.HandleFunc{b.methodNotAllowed}))
+}
+
+func defaultNotFound(c *ctx.Context) {
+	c.W.WriteHeader(http.StatusNotFound)
+	c.W.Write([]byte("not found"))
+}
+
+func defaultMethodNotAllowed(c *ctx.Context) {
+	c.W.WriteHeader(http.StatusMethodNotAllowed)
+	c.W.Write([]byte("method not allowed"))
+}
+
+func defaultOptions(c *ctx.Context) {
+	c.W.WriteHeader(http.StatusNoContent)
+}
+
+// headResponseWriter HEAD请求复用GET的handler时使用，丢弃所有的body
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
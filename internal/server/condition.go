@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// routeCondition 路由的附加条件，在匹配method之前检查，条件不满足时相当于路由不存在
+type routeCondition struct {
+	// desc 条件的描述，同时用来判断两个路由的条件是否相同
+	desc  string
+	match func(r *http.Request) bool
+}
+
+// hostCondition 限制请求的Host，忽略大小写
+// *.example.com 匹配任意子域名(不包括 example.com 本身)，pattern 不带端口时忽略请求的端口
+func hostCondition(pattern string) routeCondition {
+	pattern = strings.ToLower(pattern)
+	withPort := strings.Contains(pattern, ":")
+	wildcard := strings.HasPrefix(pattern, "*.")
+	suffix := strings.TrimPrefix(pattern, "*")
+
+	return routeCondition{
+		desc: "host:" + pattern,
+		match: func(r *http.Request) bool {
+			host := strings.ToLower(r.Host)
+			if !withPort {
+				if h, _, err := net.SplitHostPort(host); err == nil {
+					host = h
+				}
+			}
+			if wildcard {
+				return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+			}
+			return host == pattern
+		},
+	}
+}
+
+// headerCondition 要求请求头key的值等于value，value为空时只要求header存在
+func headerCondition(key, value string) routeCondition {
+	key = http.CanonicalHeaderKey(key)
+	return routeCondition{
+		desc: fmt.Sprintf("header:%s=%s", key, value),
+		match: func(r *http.Request) bool {
+			vs, ok := r.Header[key]
+			if !ok {
+				return false
+			}
+			if value == "" {
+				return true
+			}
+			for _, v := range vs {
+				if v == value {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// conditionKey 条件集合的唯一标识，和条件的顺序无关
+func conditionKey(conds []routeCondition) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	descs := make([]string, 0, len(conds))
+	for _, c := range conds {
+		descs = append(descs, c.desc)
+	}
+	sort.Strings(descs)
+	return strings.Join(descs, ",")
+}
+
+// routeList 同一个method+path下按条件区分的多个路由
+// 条件多的排在前面，条件数量相同的按注册顺序，没有条件的最多一个，放在最后
+type routeList []*Route
+
+// match 返回第一个条件满足的路由，r为nil时忽略条件
+func (l routeList) match(r *http.Request) *Route {
+	for _, rt := range l {
+		if r == nil || rt.matchConditions(r) {
+			return rt
+		}
+	}
+	return nil
+}
+
+func (l routeList) has(condKey string) bool {
+	for _, rt := range l {
+		if rt.condKey == condKey {
+			return true
+		}
+	}
+	return false
+}
+
+func (l routeList) insert(rt *Route) routeList {
+	pos := len(l)
+	for i, e := range l {
+		if len(e.conditions) < len(rt.conditions) {
+			pos = i
+			break
+		}
+	}
+	l = append(l, nil)
+	copy(l[pos+1:], l[pos:])
+	l[pos] = rt
+	return l
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"log"
+	"myserver/internal/ctx"
+	"net/http"
+	"net/http/pprof"
+)
+
+// DebugRoutesPath 内置调试路由的默认路径
+const DebugRoutesPath = "/debug/routes"
+
+// RoutesHandler 以JSON返回所有已注册的路由，每次请求时重新获取，包含之后注册的路由
+//
+//	svr.Route(http.MethodGet, server.DebugRoutesPath, server.RoutesHandler(svr))
+func RoutesHandler(s Server) ctx.HandleFunc {
+	return func(c *ctx.Context) {
+		if err := c.WriteJson(http.StatusOK, s.Routes()); err != nil {
+			log.Printf("write failed, err:%v\n", err)
+		}
+	}
+}
+
+// RegisterPprof 注册 net/http/pprof 的路由，pprof依赖完整的请求路径，所以不能用Mount
+// profile、trace 等会暴露进程信息，只应该注册在内网的admin server上
+func RegisterPprof(r Routable) {
+	r.Route(http.MethodGet, "/debug/pprof", ctx.WrapHandlerFunc(pprof.Index))
+	r.Route(http.MethodGet, "/debug/pprof/*", ctx.WrapHandlerFunc(pprof.Index))
+	r.Route(http.MethodGet, "/debug/pprof/cmdline", ctx.WrapHandlerFunc(pprof.Cmdline))
+	r.Route(http.MethodGet, "/debug/pprof/profile", ctx.WrapHandlerFunc(pprof.Profile))
+	r.Route(http.MethodGet, "/debug/pprof/symbol", ctx.WrapHandlerFunc(pprof.Symbol))
+	r.Route(http.MethodPost, "/debug/pprof/symbol", ctx.WrapHandlerFunc(pprof.Symbol))
+	r.Route(http.MethodGet, "/debug/pprof/trace", ctx.WrapHandlerFunc(pprof.Trace))
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"myserver/internal/ctx"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDispatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		wantCode     int
+		wantBody     string
+		wantAllow    string
+		wantLocation string
+	}{
+		{name: "get", method: http.MethodGet, path: "/user/list", wantCode: http.StatusOK, wantBody: "list"},
+		{name: "head uses get", method: http.MethodHead, path: "/user/list", wantCode: http.StatusOK},
+		{name: "options", method: http.MethodOptions, path: "/user/list", wantCode: http.StatusNoContent,
+			wantAllow: "GET, HEAD, OPTIONS, POST"},
+		{name: "method not allowed", method: http.MethodDelete, path: "/user/list", wantCode: http.StatusMethodNotAllowed,
+			wantBody: "method not allowed", wantAllow: "GET, HEAD, OPTIONS, POST"},
+		{name: "not found", method: http.MethodGet, path: "/nothing", wantCode: http.StatusNotFound, wantBody: "not found"},
+		{name: "not found other method", method: http.MethodDelete, path: "/nothing", wantCode: http.StatusNotFound, wantBody: "not found"},
+		{name: "clean path", method: http.MethodGet, path: "/user//./list?a=1", wantCode: http.StatusMovedPermanently,
+			wantLocation: "/user/list?a=1"},
+		{name: "remove trailing slash", method: http.MethodGet, path: "/user/list/?a=1", wantCode: http.StatusMovedPermanently,
+			wantLocation: "/user/list?a=1"},
+		{name: "add trailing slash", method: http.MethodGet, path: "/dir?a=1", wantCode: http.StatusMovedPermanently,
+			wantLocation: "/dir/?a=1"},
+		{name: "redirect keeps method", method: http.MethodPost, path: "/user/list/?a=1", wantCode: http.StatusPermanentRedirect,
+			wantLocation: "/user/list?a=1"},
+	}
+
+	// 全局中间件给所有响应加上header，404、405、OPTIONS以及重定向都要经过
+	mw := func(c *ctx.Context) {
+		c.W.Header().Set("X-Middleware", "1")
+		c.Next()
+	}
+	list := func(c *ctx.Context) {
+		c.W.Write([]byte("list"))
+	}
+	for name, h := range map[string]Handler{
+		"map":   NewMapBasedHandler(mw),
+		"tree":  NewTreeBasedHandler(mw),
+		"radix": NewRadixTreeHandler(mw),
+	} {
+		h.SetPathOptions(PathOptions{RedirectCleanPath: true, RedirectTrailingSlash: true})
+		h.Route(http.MethodGet, "/user/list", list)
+		h.Route(http.MethodPost, "/user/list", list)
+		h.Route(http.MethodGet, "/dir/", list)
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+				if w.Code != tt.wantCode {
+					t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+				}
+				if w.Body.String() != tt.wantBody && tt.wantLocation == "" {
+					t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+				}
+				if got := w.Header().Get("Allow"); got != tt.wantAllow {
+					t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+				}
+				if got := w.Header().Get("Location"); got != tt.wantLocation {
+					t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+				}
+				if w.Header().Get("X-Middleware") != "1" {
+					t.Errorf("response did not go through global middleware")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+	"myserver/internal/ctx"
+	"sort"
+	"sync"
+)
+
+// HandlerFactory 根据全局中间件创建Handler
+type HandlerFactory func(middlewares ...ctx.HandleFunc) Handler
+
+const (
+	HandlerMap   = "map"
+	HandlerTree  = "tree"
+	HandlerRadix = "radix"
+)
+
+var (
+	factoryMu sync.RWMutex
+	factories = map[string]HandlerFactory{
+		HandlerMap: func(middlewares ...ctx.HandleFunc) Handler {
+			return NewMapBasedHandler(middlewares...)
+		},
+		HandlerTree: func(middlewares ...ctx.HandleFunc) Handler {
+			return NewTreeBasedHandler(middlewares...)
+		},
+		HandlerRadix: func(middlewares ...ctx.HandleFunc) Handler {
+			return NewRadixTreeHandler(middlewares...)
+		},
+	}
+)
+
+// RegisterHandler 注册新的Handler实现，之后可以通过名字创建，重名会覆盖
+func RegisterHandler(name string, f HandlerFactory) {
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+	factories[name] = f
+}
+
+// NewHandler 按名字创建Handler，名字为空时使用tree
+func NewHandler(name string, middlewares ...ctx.HandleFunc) (Handler, error) {
+	if name == "" {
+		name = HandlerTree
+	}
+
+	factoryMu.RLock()
+	f, ok := factories[name]
+	factoryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("server: unknown handler %s, available:%v", name, handlerNames())
+	}
+	return f(middlewares...), nil
+}
+
+func handlerNames() []string {
+	factoryMu.RLock()
+	defer factoryMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
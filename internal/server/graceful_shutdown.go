@@ -24,6 +24,10 @@ type GracefulShutdown struct {
 	closing uint32
 
 	zeroReqCh chan struct{}
+	// 开始关闭时close，通知SSE、WebSocket等长连接主动结束，见 ctx.Context.Draining
+	// 长连接的handler返回之前一直计入reqCnt
+	drainCh   chan struct{}
+	drainOnce sync.Once
 }
 
 func NewGracefulShutdown() *GracefulShutdown {
@@ -31,6 +35,7 @@ func NewGracefulShutdown() *GracefulShutdown {
 		reqCnt:    0,
 		closing:   0,
 		zeroReqCh: make(chan struct{}, 2),
+		drainCh:   make(chan struct{}),
 	}
 }
 
@@ -47,6 +52,7 @@ func (g *GracefulShutdown) RejectRequestMiddleware() ctx.HandleFunc {
 
 		// 这里处理的是请求未处理完成之前服务关闭的情况
 		atomic.AddInt64(&g.reqCnt, 1)
+		c.SetDraining(g.drainCh)
 		c.Next()
 		n := atomic.AddInt64(&g.reqCnt, -1)
 
@@ -64,6 +70,10 @@ func (g *GracefulShutdown) RejectRequestMiddleware() ctx.HandleFunc {
 // 所以这里加了个补偿逻辑，如果在执行hook的时候发现请求数已经为0了，塞一个进去
 func (g *GracefulShutdown) RejectRequestAndWaiting(ctx context.Context) error {
 	atomic.StoreUint32(&g.closing, 1)
+	// 通知还在进行的长连接结束，不然只能等到超时
+	g.drainOnce.Do(func() {
+		close(g.drainCh)
+	})
 
 	// 处理服务关闭时没有请求的情况
 	n := atomic.LoadInt64(&g.reqCnt)
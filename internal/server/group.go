@@ -0,0 +1,81 @@
+package server
+
+import (
+	"io/fs"
+	"myserver/internal/ctx"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// RouteGroup 路由分组，组内的路由会统一加上前缀和中间件
+// 分组可以无限嵌套，注册时逐层往上加前缀和中间件，最终交给最外层的Routable注册
+type RouteGroup struct {
+	prefix      string
+	middlewares []ctx.HandleFunc
+	conditions  []routeCondition
+	parent      Routable
+}
+
+func newRouteGroup(parent Routable, prefix string, middlewares []ctx.HandleFunc) *RouteGroup {
+	wares := make([]ctx.HandleFunc, 0, len(middlewares))
+	wares = append(wares, middlewares...)
+	return &RouteGroup{
+		prefix:      prefix,
+		middlewares: wares,
+		parent:      parent,
+	}
+}
+
+// Route 组内中间件在路由自身的handler之前执行
+func (g *RouteGroup) Route(method, path string, handlers ...ctx.HandleFunc) *Route {
+	return g.addRoute(&routeSpec{method: method, path: path, handlers: handlers})
+}
+
+func (g *RouteGroup) addRoute(spec *routeSpec) *Route {
+	spec.path = joinPath(g.prefix, spec.path)
+	mws := make([]ctx.HandleFunc, 0, len(g.middlewares)+len(spec.middlewares))
+	mws = append(mws, g.middlewares...)
+	spec.middlewares = append(mws, spec.middlewares...)
+	spec.conditions = append(spec.conditions, g.conditions...)
+	return addRouteTo(g.parent, spec)
+}
+
+func (g *RouteGroup) Group(prefix string, middlewares ...ctx.HandleFunc) Routable {
+	return newRouteGroup(g, prefix, middlewares)
+}
+
+func (g *RouteGroup) Host(pattern string) Routable {
+	return newConditionGroup(g, hostCondition(pattern))
+}
+
+func (g *RouteGroup) Header(key, value string) Routable {
+	return newConditionGroup(g, headerCondition(key, value))
+}
+
+func (g *RouteGroup) Mount(prefix string, h http.Handler) {
+	mount(g, prefix, h)
+}
+
+func (g *RouteGroup) Static(prefix string, fsys fs.FS, opts StaticOptions) {
+	static(g, prefix, fsys, opts)
+}
+
+// newConditionGroup 没有前缀和中间件，只附加条件的分组
+func newConditionGroup(parent Routable, cond routeCondition) *RouteGroup {
+	g := newRouteGroup(parent, "", nil)
+	g.conditions = []routeCondition{cond}
+	return g
+}
+
+// joinPath 拼接前缀和路径，保留路径末尾的 /
+func joinPath(prefix, p string) string {
+	if p == "" || p == "/" {
+		return "/" + strings.Trim(prefix, "/")
+	}
+	res := path.Join("/", prefix, p)
+	if strings.HasSuffix(p, "/") {
+		res += "/"
+	}
+	return res
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"fmt"
+	"io/fs"
 	"myserver/internal/ctx"
 	"net/http"
 	"strings"
@@ -8,182 +10,312 @@ import (
 )
 
 type Routable interface {
-	Route(method, path string, hs ...ctx.HandleFunc)
+	// Route 注册路由，返回值可以用来给路由命名
+	Route(method, path string, hs ...ctx.HandleFunc) *Route
+	// Group 创建一个路由分组，组内路由统一加上prefix，并在handler之前执行middlewares
+	Group(prefix string, middlewares ...ctx.HandleFunc) Routable
+	// Host 创建一个只匹配指定Host的分组，支持 *.example.com 匹配子域名
+	Host(pattern string) Routable
+	// Header 创建一个要求请求头 key 等于 value 的分组，value为空时只要求header存在
+	Header(key, value string) Routable
+	// Mount 把任意的 http.Handler 挂载到prefix下，h收到的请求路径会去掉prefix
+	Mount(prefix string, h http.Handler)
+	// Static 把文件系统挂载到prefix下提供静态文件，支持 embed.FS
+	Static(prefix string, fsys fs.FS, opts StaticOptions)
 }
 
 type Handler interface {
 	http.Handler
 	Routable
+	// NotFound 设置路由不存在时的处理函数，会经过全局中间件
+	NotFound(h ctx.HandleFunc)
+	// MethodNotAllowed 设置method不匹配(405)时的处理函数，会经过全局中间件
+	MethodNotAllowed(h ctx.HandleFunc)
+	// Routes 按注册顺序返回所有路由
+	Routes() []RouteInfo
+	// URLFor 根据路由名字和参数生成路径
+	URLFor(name string, params ...string) (string, error)
+	// SetPathOptions 设置路由前路径规范化的配置
+	SetPathOptions(opts PathOptions)
 }
 
-// MapBasedHandler
+// MapBasedHandler 精确匹配的路由，只支持静态路径
+// 查找只有一次map访问，和路由数量无关，适合静态路由非常多的场景
 type MapBasedHandler struct {
-	routes            sync.Map
-	globalMiddlewares []ctx.HandleFunc
+	baseHandler
+	routes sync.Map // path -> map[method]routeList
 }
 
 func NewMapBasedHandler(middlewares ...ctx.HandleFunc) *MapBasedHandler {
-	wares := make([]ctx.HandleFunc, 0, len(middlewares))
-	wares = append(wares, middlewares...)
-
-	return &MapBasedHandler{
-		globalMiddlewares: wares,
-		//routes: make(map[string][]HandleFunc),
+	h := &MapBasedHandler{
+		baseHandler: newBaseHandler(middlewares),
 	}
+	h.self = h
+	return h
 }
 
-func (h *MapBasedHandler) key(method, path string) string {
-	return method + "_" + path
+// Route 实现Router接口，路径中不能包含参数和通配符
+func (h *MapBasedHandler) Route(method, path string, handlers ...ctx.HandleFunc) *Route {
+	return h.addRoute(&routeSpec{method: method, path: path, handlers: handlers})
 }
 
-// Route 实现Router接口
-func (h *MapBasedHandler) Route(method, path string, handlers ...ctx.HandleFunc) {
-	k := h.key(method, path)
-	hs := make([]ctx.HandleFunc, 0, len(handlers))
-	for i := 0; i < len(handlers); i++ {
-		hs = append(hs, handlers[i])
+func (h *MapBasedHandler) addRoute(spec *routeSpec) *Route {
+	method, path := spec.method, spec.path
+	if strings.ContainsAny(path, ":*{") {
+		panic(fmt.Sprintf("map based handler only supports static path, path:%s", path))
 	}
 
-	h.routes.Store(k, hs)
+	v, _ := h.routes.LoadOrStore(trimSlash(path), make(map[string]routeList))
+	methods := v.(map[string]routeList)
+	if methods[method].has(conditionKey(spec.conditions)) {
+		panic(fmt.Sprintf("duplicate route, method:%s, path:%s", method, path))
+	}
+	route := h.register(spec)
+	methods[method] = methods[method].insert(route)
+	return route
 }
 
 // ServeHTTP 实现http.Handler 接口
 func (h *MapBasedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	c := ctx.NewContext(w, r)
-	k := h.key(r.Method, r.URL.Path)
+	h.dispatch(w, r, h)
+}
 
-	hs, ok := h.routes.Load(k)
+func (h *MapBasedHandler) find(r *http.Request, method, path string) (*Route, ctx.Params) {
+	v, ok := h.routes.Load(trimSlash(path))
 	if !ok {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("not found"))
-		return
+		return nil, nil
 	}
+	return v.(map[string]routeList)[method].match(r), nil
+}
 
-	handlers := hs.([]ctx.HandleFunc)
-	c.Hs = h.globalMiddlewares
-	c.Hs = append(c.Hs, handlers...)
+func (h *MapBasedHandler) allowed(r *http.Request, path string) []string {
+	v, ok := h.routes.Load(trimSlash(path))
+	if !ok {
+		return nil
+	}
+	methods := v.(map[string]routeList)
+	return h.allowedMethods(func(method string) bool {
+		return methods[method].match(r) != nil
+	})
 }
 
 // TreeBasedHandler
 
 type TreeBasedHandler struct {
-	root              *Node
-	globalMiddlewares []ctx.HandleFunc
+	baseHandler
+	root *Node
 }
 
 func NewTreeBasedHandler(middlewares ...ctx.HandleFunc) *TreeBasedHandler {
-	wares := make([]ctx.HandleFunc, 0, len(middlewares))
-	wares = append(wares, middlewares...)
-	return &TreeBasedHandler{
-		root:              NewNode("/"),
-		globalMiddlewares: wares,
+	h := &TreeBasedHandler{
+		baseHandler: newBaseHandler(middlewares),
+		root:        NewNode("/"),
 	}
+	h.self = h
+	return h
 }
 
 func (h *TreeBasedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	handlers := h.Query(h.root, r.Method, r.URL.Path)
-	if handlers == nil {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("not found"))
-		return
+	h.dispatch(w, r, h)
+}
+
+func (h *TreeBasedHandler) find(r *http.Request, method, path string) (*Route, ctx.Params) {
+	paths := strings.Split(strings.Trim(path, "/"), "/")
+	var params ctx.Params
+	route := h.root.query(paths, method, r, &params)
+	if route == nil {
+		return nil, nil
 	}
+	return route, params
+}
 
-	c := ctx.NewContext(w, r)
-	c.Hs = h.globalMiddlewares
-	c.Hs = append(c.Hs, handlers...)
-	//log.Printf("len of handler:%d\n", len(c.Hs))
-	c.Next()
+func (h *TreeBasedHandler) allowed(r *http.Request, path string) []string {
+	paths := strings.Split(strings.Trim(path, "/"), "/")
+	var params ctx.Params
+	return h.allowedMethods(func(method string) bool {
+		params = params[:0]
+		return h.root.query(paths, method, r, &params) != nil
+	})
 }
 
-// 如果重复注册的话，这里其实不会报错，也不会生效
-func (h *TreeBasedHandler) Route(method, path string, handlers ...ctx.HandleFunc) {
+// Route 注册路由，同一个路径可以注册多个method
+// 同样的method+path重复注册会直接panic
+func (h *TreeBasedHandler) Route(method, path string, handlers ...ctx.HandleFunc) *Route {
+	return h.addRoute(&routeSpec{method: method, path: path, handlers: handlers})
+}
+
+func (h *TreeBasedHandler) addRoute(spec *routeSpec) *Route {
+	method, path := spec.method, spec.path
 	cur := h.root
 	paths := strings.Split(strings.Trim(path, "/"), "/")
-
-	// 检查通配符 * 是否位于末尾
-	wildcardPos := strings.Index(path, "*")
-	if wildcardPos != -1 && wildcardPos != len(path)-1 {
-		panic("illegal wildcard position, should appear only once and at the end of path")
-	}
+	checkPattern(path, paths)
 
 	for idx, p := range paths {
 		subNode, ok := cur.Match(p, false)
-		if ok {
-			cur = subNode
-		} else {
+		if !ok {
 			// create
-			h.createSubTree(cur, method, paths[idx:], handlers...)
-			return
+			cur = h.createSubTree(cur, paths[idx:])
+			break
 		}
+		cur = subNode
+	}
+
+	if cur.routes[method].has(conditionKey(spec.conditions)) {
+		panic(fmt.Sprintf("duplicate route, method:%s, path:%s", method, path))
 	}
+	// 叶子结点添加handler
+	route := h.register(spec)
+	cur.routes[method] = cur.routes[method].insert(route)
+	cur.isLeaf = true
+	return route
 }
 
-// 在root下建子树
-func (h *TreeBasedHandler) createSubTree(root *Node, method string, path []string, handlers ...ctx.HandleFunc) {
+// 在root下建子树，返回最后一段对应的节点
+func (h *TreeBasedHandler) createSubTree(root *Node, path []string) *Node {
 	cur := root
 	for _, p := range path {
 		node := NewNode(p)
-		cur.child = append(cur.child, node)
+		cur.addChild(node)
 		cur = node
 	}
-	cur.method = method
-	//log.Printf("pattern:%s, method:%s\n", cur.path, method)
-	cur.isLeaf = true
-	// 叶子结点添加handler
-	for i := range handlers {
-		cur.fns = append(cur.fns, handlers[i])
-	}
+	return cur
 }
 
-func (h *TreeBasedHandler) Query(root *Node, method string, path string) []ctx.HandleFunc {
+// Query 查找路由，返回对应的调用链以及匹配到的路径参数
+// 匹配优先级：静态节点 > 参数节点(:id) > 通配符节点(*)
+// 同一位置有多个参数节点时，有约束的按注册顺序先尝试，没有约束的最后尝试
+// 高优先级的分支后续匹配失败时会回溯，尝试低优先级的分支
+func (h *TreeBasedHandler) Query(root *Node, method string, path string) ([]ctx.HandleFunc, ctx.Params) {
 	paths := strings.Split(strings.Trim(path, "/"), "/")
-	cur := root
-	for _, p := range paths {
-		//log.Printf("p:%s\n", p)
-		n, ok := cur.Match(p, true)
-		if !ok {
-			return nil
-		}
-		cur = n
-	}
-	if cur.method != method {
-		//log.Printf("method not match\n")
-		return nil
+	var params ctx.Params
+	route := root.query(paths, method, nil, &params)
+	if route == nil {
+		return nil, nil
 	}
-	return cur.fns
+	return route.chain, params
 }
 
+type nodeType int
+
+const (
+	nodeStatic   nodeType = iota // 静态节点，精确匹配
+	nodeParam                    // 参数节点，形如 :id 或 {id:[0-9]+}，匹配一段
+	nodeWildcard                 // 通配符节点 *，匹配剩余的所有段
+)
+
 type Node struct {
 	path   string
-	method string // 只有路由的最后一段才会赋值
-	isLeaf bool   // 其实没有用到
+	typ    nodeType
+	isLeaf bool // 标记是否注册过路由
+
+	child         []*Node // 静态子节点
+	paramChildren []*Node // 参数子节点，有约束的在前，没有约束的最多一个，放在最后
+	wildcardChild *Node
+	param         *paramMatcher // 参数节点的参数名和约束
 
-	child []*Node
-	fns   []ctx.HandleFunc
+	// method -> 路由，只有路由的最后一段才会有值
+	routes map[string]routeList
 }
 
 func NewNode(path string) *Node {
+	typ := nodeStatic
+	param := parseParam(path)
+	switch {
+	case path == "*":
+		typ = nodeWildcard
+	case param != nil:
+		typ = nodeParam
+	}
 	return &Node{
 		path:   path,
+		typ:    typ,
+		param:  param,
 		child:  make([]*Node, 0, 4),
 		isLeaf: false,
-		fns:    nil,
+		routes: make(map[string]routeList),
+	}
+}
+
+func (n *Node) addChild(ch *Node) {
+	switch ch.typ {
+	case nodeParam:
+		pos := paramInsertPos(n.params(), ch.param)
+		n.paramChildren = append(n.paramChildren, nil)
+		copy(n.paramChildren[pos+1:], n.paramChildren[pos:])
+		n.paramChildren[pos] = ch
+	case nodeWildcard:
+		n.wildcardChild = ch
+	default:
+		n.child = append(n.child, ch)
+	}
+}
+
+func (n *Node) params() []*paramMatcher {
+	ms := make([]*paramMatcher, 0, len(n.paramChildren))
+	for _, ch := range n.paramChildren {
+		ms = append(ms, ch.param)
 	}
+	return ms
 }
 
-// 这里在寻找路由的时候需要支持通配符匹配
-// 但是在添加路由的时候不需要支持
+// Match 用于注册路由时查找已存在的子节点，只做精确匹配
+// 同一位置已经存在约束相同但名字不同的参数节点时，两个路由无法区分，直接panic
+// 查询时的匹配见 query
 func (n *Node) Match(path string, enableWildcard bool) (*Node, bool) {
-	// 首先肯定精确匹配，过程中如果匹配到了通配符，先记录下来
-	var wildcardMatch *Node
+	if path == "*" {
+		return n.wildcardChild, n.wildcardChild != nil
+	}
+	if m := parseParam(path); m != nil {
+		if idx := findParam(n.params(), m); idx >= 0 {
+			return n.paramChildren[idx], true
+		}
+		return nil, false
+	}
+
 	for _, ch := range n.child {
-		if ch.path == path && ch.path != "*" {
+		if ch.path == path {
 			return ch, true
 		}
+	}
+	if enableWildcard && n.wildcardChild != nil {
+		return n.wildcardChild, true
+	}
+	return nil, false
+}
+
+// query 在当前节点下匹配剩余的路径，按优先级深度优先搜索
+// 叶子节点需要注册了method，并且路由的Host等条件满足r，r为nil时不检查条件
+func (n *Node) query(paths []string, method string, r *http.Request, params *ctx.Params) *Route {
+	if len(paths) == 0 {
+		return n.routes[method].match(r)
+	}
+	p := paths[0]
+
+	for _, ch := range n.child {
+		if ch.path == p {
+			if res := ch.query(paths[1:], method, r, params); res != nil {
+				return res
+			}
+			break
+		}
+	}
+
+	for _, ch := range n.paramChildren {
+		if !ch.param.match(p) {
+			continue
+		}
+		*params = append(*params, ctx.Param{Key: ch.param.name, Value: p})
+		if res := ch.query(paths[1:], method, r, params); res != nil {
+			return res
+		}
+		*params = (*params)[:len(*params)-1]
+	}
 
-		if enableWildcard && ch.path == "*" {
-			wildcardMatch = ch
+	if ch := n.wildcardChild; ch != nil {
+		if res := ch.routes[method].match(r); res != nil {
+			*params = append(*params, ctx.Param{Key: "*", Value: strings.Join(paths, "/")})
+			return res
 		}
 	}
-	return wildcardMatch, wildcardMatch != nil
+	return nil
 }
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"myserver/internal/ctx"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 静态路由，模拟一个中等规模的业务
+var benchStaticRoutes = []string{
+	"/",
+	"/user/list",
+	"/user/signup",
+	"/user/login",
+	"/user/logout",
+	"/user/profile",
+	"/user/profile/avatar",
+	"/user/settings",
+	"/user/settings/notification",
+	"/user/settings/privacy",
+	"/order/list",
+	"/order/create",
+	"/order/cancel",
+	"/order/refund",
+	"/order/stat/daily",
+	"/order/stat/monthly",
+	"/mq/push",
+	"/mq/exchange/create",
+	"/mq/queue/declare_bind",
+	"/mq/kafka/publist",
+	"/admin/user/list",
+	"/admin/user/ban",
+	"/admin/order/list",
+	"/admin/config/reload",
+	"/health",
+	"/metrics",
+}
+
+var benchParamRoutes = []string{
+	"/user/:id",
+	"/user/:id/detail",
+	"/order/:oid/item/:iid",
+	"/static/*",
+}
+
+func nopHandler(c *ctx.Context) {}
+
+type nopResponseWriter struct {
+	h http.Header
+}
+
+func (w *nopResponseWriter) Header() http.Header         { return w.h }
+func (w *nopResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *nopResponseWriter) WriteHeader(int)             {}
+
+func newBenchHandler(h Handler, withParams bool) Handler {
+	for _, p := range benchStaticRoutes {
+		h.Route(http.MethodGet, p, nopHandler)
+	}
+	if withParams {
+		for _, p := range benchParamRoutes {
+			h.Route(http.MethodGet, p, nopHandler)
+		}
+	}
+	return h
+}
+
+func benchServe(b *testing.B, h Handler, path string) {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	w := &nopResponseWriter{h: make(http.Header)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, r)
+	}
+}
+
+func benchFind(b *testing.B, f routeFinder, path string) {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if route, _ := f.find(r, http.MethodGet, path); route == nil {
+			b.Fatalf("route %s not found", path)
+		}
+	}
+}
+
+// ServeHTTP 整体的开销，包括创建Context
+
+func BenchmarkMapBasedHandler_Static(b *testing.B) {
+	benchServe(b, newBenchHandler(NewMapBasedHandler(), false), "/admin/config/reload")
+}
+
+func BenchmarkTreeBasedHandler_Static(b *testing.B) {
+	benchServe(b, newBenchHandler(NewTreeBasedHandler(), false), "/admin/config/reload")
+}
+
+func BenchmarkRadixTreeHandler_Static(b *testing.B) {
+	benchServe(b, newBenchHandler(NewRadixTreeHandler(), false), "/admin/config/reload")
+}
+
+func BenchmarkTreeBasedHandler_Param(b *testing.B) {
+	benchServe(b, newBenchHandler(NewTreeBasedHandler(), true), "/order/123/item/456")
+}
+
+func BenchmarkRadixTreeHandler_Param(b *testing.B) {
+	benchServe(b, newBenchHandler(NewRadixTreeHandler(), true), "/order/123/item/456")
+}
+
+// 只比较路由查找
+
+func BenchmarkTreeBasedHandler_FindStatic(b *testing.B) {
+	benchFind(b, newBenchHandler(NewTreeBasedHandler(), true).(routeFinder), "/user/settings/notification")
+}
+
+func BenchmarkRadixTreeHandler_FindStatic(b *testing.B) {
+	benchFind(b, newBenchHandler(NewRadixTreeHandler(), true).(routeFinder), "/user/settings/notification")
+}
+
+func BenchmarkTreeBasedHandler_FindParam(b *testing.B) {
+	benchFind(b, newBenchHandler(NewTreeBasedHandler(), true).(routeFinder), "/user/123/detail")
+}
+
+func BenchmarkRadixTreeHandler_FindParam(b *testing.B) {
+	benchFind(b, newBenchHandler(NewRadixTreeHandler(), true).(routeFinder), "/user/123/detail")
+}
+
+func BenchmarkTreeBasedHandler_FindWildcard(b *testing.B) {
+	benchFind(b, newBenchHandler(NewTreeBasedHandler(), true).(routeFinder), "/static/js/app/main.js")
+}
+
+func BenchmarkRadixTreeHandler_FindWildcard(b *testing.B) {
+	benchFind(b, newBenchHandler(NewRadixTreeHandler(), true).(routeFinder), "/static/js/app/main.js")
+}
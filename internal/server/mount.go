@@ -0,0 +1,52 @@
+package server
+
+import (
+	"myserver/internal/ctx"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountMethods Mount 时注册的method，HEAD和OPTIONS也交给被挂载的handler处理
+var mountMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// mount 把h挂载到prefix下，prefix本身和prefix下的所有路径都交给h处理
+// h收到的请求路径去掉了prefix，全局中间件和分组中间件同样生效
+// 依赖通配符路由，MapBasedHandler不支持
+func mount(r Routable, prefix string, h http.Handler) {
+	f := stripPrefix(h)
+	for _, m := range mountMethods {
+		if route := r.Route(m, prefix, f); route != nil {
+			route.keepSlash = true
+		}
+		r.Route(m, joinPath(prefix, "*"), f)
+	}
+}
+
+// stripPrefix 用通配符匹配到的部分作为新的路径，保留末尾的 /
+func stripPrefix(h http.Handler) ctx.HandleFunc {
+	return func(c *ctx.Context) {
+		rest := c.Param("*")
+		if rest != "" && strings.HasSuffix(c.R.URL.Path, "/") {
+			rest += "/"
+		}
+
+		r2 := new(http.Request)
+		*r2 = *c.R
+		r2.URL = new(url.URL)
+		*r2.URL = *c.R.URL
+		r2.URL.Path = "/" + rest
+		r2.URL.RawPath = ""
+		h.ServeHTTP(c.W, r2)
+	}
+}
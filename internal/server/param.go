@@ -0,0 +1,140 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 路径参数支持两种写法：
+//   :id            匹配任意非空的一段
+//   {id}           同 :id
+//   {id:[0-9]+}    用正则约束，需要完整匹配这一段
+//   {name:slug}    使用内置的类型约束，见 paramTypes
+// 约束在注册路由时编译，不满足约束的请求会继续尝试其他路由
+
+// paramTypes 内置的类型约束
+var paramTypes = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"slug":  `[a-z0-9]+(?:-[a-z0-9]+)*`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// 编译过的约束，同样的约束只编译一次
+var constraintCache sync.Map
+
+type paramMatcher struct {
+	name       string
+	constraint string // 展开类型后的正则，用来判断两个参数是否相同
+	re         *regexp.Regexp
+}
+
+// match 参数值不能为空，有约束的话需要满足约束
+func (m *paramMatcher) match(v string) bool {
+	return v != "" && (m.re == nil || m.re.MatchString(v))
+}
+
+func (m *paramMatcher) String() string {
+	if m.re == nil {
+		return ":" + m.name
+	}
+	return "{" + m.name + ":" + m.constraint + "}"
+}
+
+func isParamSegment(seg string) bool {
+	return strings.HasPrefix(seg, ":") || (strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"))
+}
+
+// parseParam 解析一段路由，不是参数时返回nil，约束不合法时panic
+func parseParam(seg string) *paramMatcher {
+	if strings.HasPrefix(seg, ":") {
+		return &paramMatcher{name: seg[1:]}
+	}
+	if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+		return nil
+	}
+
+	body := seg[1 : len(seg)-1]
+	idx := strings.Index(body, ":")
+	if idx < 0 {
+		return &paramMatcher{name: body}
+	}
+	m := &paramMatcher{name: body[:idx]}
+	m.constraint = body[idx+1:]
+	if expr, ok := paramTypes[m.constraint]; ok {
+		m.constraint = expr
+	}
+	if m.constraint == "" {
+		panic(fmt.Sprintf("empty constraint for param %s", m.name))
+	}
+	m.re = compileConstraint(m.constraint)
+	return m
+}
+
+func compileConstraint(expr string) *regexp.Regexp {
+	if re, ok := constraintCache.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	// 约束需要完整匹配一段
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("illegal param constraint %s, err:%v", expr, err))
+	}
+	constraintCache.Store(expr, re)
+	return re
+}
+
+// findParam 在同一位置已有的参数中查找和m相同(名字和约束都相同)的参数，找不到返回-1
+// 约束相同但名字不同时两个路由无法区分，直接panic
+func findParam(existing []*paramMatcher, m *paramMatcher) int {
+	for i, e := range existing {
+		if e.constraint != m.constraint {
+			continue
+		}
+		if e.name != m.name {
+			panic(fmt.Sprintf("param %s conflicts with existing param %s", m, e))
+		}
+		return i
+	}
+	return -1
+}
+
+// paramInsertPos 新参数插入的位置：有约束的按注册顺序排在前面，没有约束的放在最后
+func paramInsertPos(existing []*paramMatcher, m *paramMatcher) int {
+	if m.re == nil {
+		return len(existing)
+	}
+	for i, e := range existing {
+		if e.re == nil {
+			return i
+		}
+	}
+	return len(existing)
+}
+
+// 检查路由是否合法：通配符只能出现在末尾，参数名不能为空，同一个路由里参数名不能重复
+func checkPattern(path string, paths []string) {
+	names := make(map[string]struct{}, len(paths))
+	for i, p := range paths {
+		// 检查通配符 * 是否位于末尾
+		if strings.Contains(p, "*") && !isParamSegment(p) && (p != "*" || i != len(paths)-1) {
+			panic("illegal wildcard position, should appear only once and at the end of path")
+		}
+
+		m := parseParam(p)
+		if m == nil {
+			continue
+		}
+		if m.name == "" {
+			panic(fmt.Sprintf("empty param name in path %s", path))
+		}
+		if _, ok := names[m.name]; ok {
+			panic(fmt.Sprintf("duplicate param name %s in path %s", m.name, path))
+		}
+		names[m.name] = struct{}{}
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"myserver/internal/ctx"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// PathOptions 路由前对请求路径的规范化，默认全部关闭
+type PathOptions struct {
+	// CleanPath 路由前清理路径：处理 . 和 ..，合并连续的 /，保留末尾的 /
+	CleanPath bool
+	// RedirectCleanPath 路径不规范时重定向到清理后的路径，而不是直接按清理后的路径路由
+	RedirectCleanPath bool
+	// RedirectTrailingSlash 请求路径末尾的 / 和注册的路由不一致时，重定向到注册的写法
+	// 比如注册的是 /user/list，请求 /user/list/ 会重定向到 /user/list，反之亦然
+	RedirectTrailingSlash bool
+}
+
+// SetPathOptions 设置路径规范化的配置，需要在启动前设置
+func (b *baseHandler) SetPathOptions(opts PathOptions) {
+	b.pathOpts = opts
+}
+
+// cleanPath 和 path.Clean 一样，但是保证以 / 开头并且保留末尾的 /
+// 路径已经是规范的话不会分配内存
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		// 避免已经规范的路径重新拼接分配内存
+		if len(p) == len(np)+1 && strings.HasPrefix(p, np) {
+			return p
+		}
+		np += "/"
+	}
+	return np
+}
+
+// trailingSlashTarget 根据注册的路由计算末尾 / 需要调整后的路径，不需要调整时返回空
+// 通配符路由、Static和Mount的路由以及根路径不处理
+func trailingSlashTarget(route *Route, p string) string {
+	pattern := route.info.Pattern
+	if p == "/" || route.keepSlash || strings.HasSuffix(pattern, "*") {
+		return ""
+	}
+	want := len(pattern) > 1 && strings.HasSuffix(pattern, "/")
+	has := strings.HasSuffix(p, "/")
+	switch {
+	case want && !has:
+		return p + "/"
+	case !want && has:
+		return strings.TrimRight(p, "/")
+	}
+	return ""
+}
+
+// redirect 重定向到target，保留query，GET/HEAD 使用301，其余使用308保证method和body不变
+func redirect(target string) ctx.HandleFunc {
+	// 开头的多个 / 会被当成 //host 这种协议相对的地址，这里合并掉，防止跳转到别的站点
+	target = "/" + strings.TrimLeft(target, "/")
+	return func(c *ctx.Context) {
+		u := *c.R.URL
+		u.Path = target
+		u.RawPath = ""
+
+		code := http.StatusMovedPermanently
+		if c.R.Method != http.MethodGet && c.R.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(c.W, c.R, u.RequestURI(), code)
+	}
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"fmt"
+	"myserver/internal/ctx"
+	"net/http"
+	"strings"
+)
+
+// RadixTreeHandler 基于压缩前缀树(radix tree)的路由，支持的路由规则和TreeBasedHandler一致
+// 和TreeBasedHandler相比：
+// 1. 有公共前缀的静态路径合并到同一条边上，节点更少
+// 2. 静态子节点按首字节建立索引，不需要逐个比较整段路径
+// 3. 查找时不切分路径，静态路由的查找没有内存分配
+type RadixTreeHandler struct {
+	baseHandler
+	root *radixNode
+}
+
+func NewRadixTreeHandler(middlewares ...ctx.HandleFunc) *RadixTreeHandler {
+	h := &RadixTreeHandler{
+		baseHandler: newBaseHandler(middlewares),
+		root:        &radixNode{},
+	}
+	h.self = h
+	return h
+}
+
+func (h *RadixTreeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.dispatch(w, r, h)
+}
+
+// Route 注册路由，同样的method+path重复注册会直接panic
+func (h *RadixTreeHandler) Route(method, path string, handlers ...ctx.HandleFunc) *Route {
+	return h.addRoute(&routeSpec{method: method, path: path, handlers: handlers})
+}
+
+func (h *RadixTreeHandler) addRoute(spec *routeSpec) *Route {
+	method, path := spec.method, spec.path
+	paths := strings.Split(strings.Trim(path, "/"), "/")
+	checkPattern(path, paths)
+
+	n := h.root.insert(paths)
+	if n.routes[method].has(conditionKey(spec.conditions)) {
+		panic(fmt.Sprintf("duplicate route, method:%s, path:%s", method, path))
+	}
+	if n.routes == nil {
+		n.routes = make(map[string]routeList)
+	}
+	route := h.register(spec)
+	n.routes[method] = n.routes[method].insert(route)
+	return route
+}
+
+func (h *RadixTreeHandler) find(r *http.Request, method, path string) (*Route, ctx.Params) {
+	route, params := h.root.lookup(trimSlash(path), method, r, nil)
+	if route == nil {
+		return nil, nil
+	}
+	return route, params
+}
+
+func (h *RadixTreeHandler) allowed(r *http.Request, path string) []string {
+	path = trimSlash(path)
+	var params ctx.Params
+	return h.allowedMethods(func(method string) bool {
+		route, _ := h.root.lookup(path, method, r, params[:0])
+		return route != nil
+	})
+}
+
+// trimSlash 和TreeBasedHandler的行为保持一致：忽略末尾的 /，开头连续的 / 当成一个
+// 只做切片，不分配内存
+func trimSlash(path string) string {
+	for len(path) > 1 && path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	}
+	for len(path) > 1 && path[1] == '/' {
+		path = path[1:]
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+type radixNode struct {
+	prefix string // 静态边上的内容
+
+	// indices[i] 是 children[i].prefix 的首字节，查找时先比较首字节
+	indices  []byte
+	children []*radixNode
+
+	param         *paramMatcher // 参数节点的参数名和约束
+	paramChildren []*radixNode  // 有约束的在前，没有约束的最多一个，放在最后
+	wildcardChild *radixNode
+
+	// method -> 路由
+	routes map[string]routeList
+}
+
+// insert 按段插入路由，连续的静态段拼成一条边，返回路由最后一段对应的节点
+func (n *radixNode) insert(paths []string) *radixNode {
+	cur := n
+	static := ""
+	for _, p := range paths {
+		switch {
+		case p == "*":
+			cur = cur.insertStatic(static + "/")
+			static = ""
+			if cur.wildcardChild == nil {
+				cur.wildcardChild = &radixNode{}
+			}
+			cur = cur.wildcardChild
+		case isParamSegment(p):
+			cur = cur.insertStatic(static + "/")
+			static = ""
+			cur = cur.insertParam(parseParam(p))
+		default:
+			static += "/" + p
+		}
+	}
+	return cur.insertStatic(static)
+}
+
+// insertStatic 插入一段静态路径，和已有的边有公共前缀时拆分已有的边
+func (n *radixNode) insertStatic(path string) *radixNode {
+	if path == "" {
+		return n
+	}
+
+	for i, c := range n.indices {
+		if c != path[0] {
+			continue
+		}
+		child := n.children[i]
+		l := commonPrefixLen(path, child.prefix)
+		if l < len(child.prefix) {
+			// 拆分：公共前缀作为新的节点，原来的节点挂在它下面
+			split := &radixNode{
+				prefix:   child.prefix[:l],
+				indices:  []byte{child.prefix[l]},
+				children: []*radixNode{child},
+			}
+			child.prefix = child.prefix[l:]
+			n.children[i] = split
+			child = split
+		}
+		return child.insertStatic(path[l:])
+	}
+
+	child := &radixNode{prefix: path}
+	n.indices = append(n.indices, path[0])
+	n.children = append(n.children, child)
+	return child
+}
+
+// insertParam 同一位置约束相同但名字不同的参数无法区分，直接panic
+func (n *radixNode) insertParam(m *paramMatcher) *radixNode {
+	existing := make([]*paramMatcher, 0, len(n.paramChildren))
+	for _, ch := range n.paramChildren {
+		existing = append(existing, ch.param)
+	}
+	if idx := findParam(existing, m); idx >= 0 {
+		return n.paramChildren[idx]
+	}
+
+	child := &radixNode{param: m}
+	pos := paramInsertPos(existing, m)
+	n.paramChildren = append(n.paramChildren, nil)
+	copy(n.paramChildren[pos+1:], n.paramChildren[pos:])
+	n.paramChildren[pos] = child
+	return child
+}
+
+// lookup 在当前节点下匹配剩余的路径，优先级为 静态 > 参数 > 通配符，匹配失败时回溯
+// 叶子节点需要注册了method，并且路由的Host等条件满足r，r为nil时不检查条件
+// params 按值传递，回溯时直接丢弃追加的部分
+func (n *radixNode) lookup(path, method string, r *http.Request, params ctx.Params) (*Route, ctx.Params) {
+	if path == "" {
+		if res := n.routes[method].match(r); res != nil {
+			return res, params
+		}
+		// 末尾的 / 已经去掉，只有根路径会走到这里，和TreeBasedHandler一样由 /* 匹配，参数为空
+		if ch := n.wildcardChild; ch != nil {
+			if res := ch.routes[method].match(r); res != nil {
+				return res, append(params, ctx.Param{Key: "*", Value: ""})
+			}
+		}
+		return nil, params
+	}
+
+	c := path[0]
+	for i, idx := range n.indices {
+		if idx != c {
+			continue
+		}
+		child := n.children[i]
+		if strings.HasPrefix(path, child.prefix) {
+			if res, ps := child.lookup(path[len(child.prefix):], method, r, params); res != nil {
+				return res, ps
+			}
+		}
+		break
+	}
+
+	// 参数节点只会挂在以 / 结尾的静态边之后，所以这里的path一定从一段的开头开始
+	if len(n.paramChildren) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		seg := path[:end]
+		for _, ch := range n.paramChildren {
+			if !ch.param.match(seg) {
+				continue
+			}
+			ps := append(params, ctx.Param{Key: ch.param.name, Value: seg})
+			if res, ps := ch.lookup(path[end:], method, r, ps); res != nil {
+				return res, ps
+			}
+		}
+	}
+
+	if ch := n.wildcardChild; ch != nil {
+		if res := ch.routes[method].match(r); res != nil {
+			return res, append(params, ctx.Param{Key: "*", Value: path})
+		}
+	}
+	return nil, params
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"fmt"
+	"myserver/internal/ctx"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// RouteInfo 已注册路由的信息，用于核对部署以及生成文档
+type RouteInfo struct {
+	Name    string `json:"name,omitempty"`
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	// 路由自身的handler名字，不包含中间件
+	Handlers []string `json:"handlers"`
+	// 全局中间件和分组中间件的总数
+	Middlewares int `json:"middlewares"`
+	// Host、Header 等附加条件
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+// Route 注册路由的返回值
+//
+//	svr.Route(http.MethodGet, "/user/:id", user.Detail).Name("user.detail")
+type Route struct {
+	info  *RouteInfo
+	chain []ctx.HandleFunc // 全局中间件+分组中间件+handlers
+	base  *baseHandler
+
+	conditions []routeCondition
+	condKey    string
+
+	// keepSlash Static、Mount 注册的路由，末尾的 / 由挂载的handler自己处理，不做 RedirectTrailingSlash
+	keepSlash bool
+}
+
+// Name 给路由命名，之后可以通过 URLFor 生成路径，名字重复会panic
+func (r *Route) Name(name string) *Route {
+	r.base.setName(r.info, name)
+	return r
+}
+
+// Info 返回路由信息
+func (r *Route) Info() RouteInfo {
+	return *r.info
+}
+
+func (r *Route) matchConditions(req *http.Request) bool {
+	for _, c := range r.conditions {
+		if !c.match(req) {
+			return false
+		}
+	}
+	return true
+}
+
+// routeSpec 注册一个路由需要的完整信息，分组注册时逐层补充前缀和中间件
+type routeSpec struct {
+	method      string
+	path        string
+	middlewares []ctx.HandleFunc // 分组中间件，不包含全局中间件
+	handlers    []ctx.HandleFunc
+	conditions  []routeCondition
+}
+
+// funcs 分组中间件+路由自身的handler
+func (s *routeSpec) funcs() []ctx.HandleFunc {
+	hs := make([]ctx.HandleFunc, 0, len(s.middlewares)+len(s.handlers))
+	hs = append(hs, s.middlewares...)
+	hs = append(hs, s.handlers...)
+	return hs
+}
+
+// routeAdder 包内的Routable都实现了这个接口，分组通过它把中间件等信息原样传下去
+type routeAdder interface {
+	addRoute(spec *routeSpec) *Route
+}
+
+// addRouteTo 外部实现的Routable没有addRoute，退化成普通的Route
+func addRouteTo(r Routable, spec *routeSpec) *Route {
+	if a, ok := r.(routeAdder); ok {
+		return a.addRoute(spec)
+	}
+	return r.Route(spec.method, spec.path, spec.funcs()...)
+}
+
+// funcName 返回函数名，方法值会带上 -fm 后缀，这里去掉
+func funcName(f ctx.HandleFunc) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	return strings.TrimSuffix(fn.Name(), "-fm")
+}
+
+// buildURL 用参数填充路由中的参数和 *，params 为 key, value 交替的列表
+// 参数缺失、多余、为空或者不满足约束都会返回错误
+func buildURL(pattern string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("server: odd number of params for %s", pattern)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	paths := strings.Split(strings.Trim(pattern, "/"), "/")
+	used := 0
+	for i, p := range paths {
+		var key string
+		m := parseParam(p)
+		switch {
+		case p == "*":
+			key = "*"
+		case m != nil:
+			key = m.name
+		default:
+			continue
+		}
+
+		v, ok := values[key]
+		if !ok || v == "" {
+			return "", fmt.Errorf("server: missing param %s for %s", key, pattern)
+		}
+		if m != nil && !m.match(v) {
+			return "", fmt.Errorf("server: param %s=%s does not match %s", key, v, m)
+		}
+		used++
+		if key == "*" {
+			// 通配符可以包含多段，每段单独转义
+			segs := strings.Split(strings.Trim(v, "/"), "/")
+			for j := range segs {
+				segs[j] = url.PathEscape(segs[j])
+			}
+			paths[i] = strings.Join(segs, "/")
+			continue
+		}
+		paths[i] = url.PathEscape(v)
+	}
+	if used != len(values) {
+		return "", fmt.Errorf("server: unknown params %v for %s", params, pattern)
+	}
+
+	res := "/" + strings.Join(paths, "/")
+	if len(res) > 1 && strings.HasSuffix(pattern, "/") {
+		res += "/"
+	}
+	return res, nil
+}
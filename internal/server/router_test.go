@@ -0,0 +1,160 @@
+package server
+
+import (
+	"myserver/internal/ctx"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// parityRoutes 同时注册到 TreeBasedHandler 和 RadixTreeHandler，两者的匹配结果必须一致
+var parityRoutes = []string{
+	"/",
+	"/user/list",
+	"/user/:id",
+	"/user/:id/detail",
+	"/user/{id:int}/posts",
+	"/order/{oid:uint}/item/{iid:[0-9]+}",
+	"/code/{code:[a-z]{3}}",
+	"/token/{id:uuid}",
+	"/code/:any",
+	// 有约束的分支匹配失败后回溯到没有约束的参数
+	"/file/{name:alpha}/raw",
+	"/file/:any/info",
+	// 静态分支匹配失败后回溯到参数
+	"/a/b/c",
+	"/a/:x/d",
+	// 参数分支匹配失败后回溯到通配符
+	"/doc/:ver/index",
+	"/doc/*",
+	"/static/*",
+}
+
+// rootWildcardRoutes 根路径只注册了 /* 时，/ 也由 /* 匹配，参数为空
+var rootWildcardRoutes = []string{
+	"/*",
+	"/doc/*",
+}
+
+func newParityHandlers(routes []string) map[string]Handler {
+	hs := map[string]Handler{
+		"tree":  NewTreeBasedHandler(),
+		"radix": NewRadixTreeHandler(),
+	}
+	for _, h := range hs {
+		for _, p := range routes {
+			h.Route(http.MethodGet, p, nopHandler)
+		}
+	}
+	return hs
+}
+
+func formatParams(ps ctx.Params) string {
+	res := make([]string, 0, len(ps))
+	for _, p := range ps {
+		res = append(res, p.Key+"="+p.Value)
+	}
+	return strings.Join(res, ",")
+}
+
+type parityCase struct {
+	path string
+	// wantPattern 为空表示找不到路由
+	wantPattern string
+	wantParams  string
+}
+
+func TestRouterParity(t *testing.T) {
+	tests := []parityCase{
+		{path: "/", wantPattern: "/"},
+		{path: "/user/list", wantPattern: "/user/list"},
+		{path: "/user/list/", wantPattern: "/user/list"},
+		{path: "/user/42", wantPattern: "/user/:id", wantParams: "id=42"},
+		{path: "/user/tom/detail", wantPattern: "/user/:id/detail", wantParams: "id=tom"},
+		{path: "/user/-7/posts", wantPattern: "/user/{id:int}/posts", wantParams: "id=-7"},
+		{path: "/user/tom/posts"},
+		{path: "/order/12/item/34", wantPattern: "/order/{oid:uint}/item/{iid:[0-9]+}", wantParams: "oid=12,iid=34"},
+		{path: "/order/12/item/x"},
+		{path: "/order/-1/item/34"},
+		{path: "/code/abc", wantPattern: "/code/{code:[a-z]{3}}", wantParams: "code=abc"},
+		{path: "/code/abcd", wantPattern: "/code/:any", wantParams: "any=abcd"},
+		{path: "/token/123e4567-e89b-12d3-a456-426614174000", wantPattern: "/token/{id:uuid}", wantParams: "id=123e4567-e89b-12d3-a456-426614174000"},
+		{path: "/token/123"},
+		{path: "/file/abc/raw", wantPattern: "/file/{name:alpha}/raw", wantParams: "name=abc"},
+		{path: "/file/abc/info", wantPattern: "/file/:any/info", wantParams: "any=abc"},
+		{path: "/file/a1/raw"},
+		{path: "/a/b/c", wantPattern: "/a/b/c"},
+		{path: "/a/b/d", wantPattern: "/a/:x/d", wantParams: "x=b"},
+		{path: "/a/b/e"},
+		{path: "/doc/v1/index", wantPattern: "/doc/:ver/index", wantParams: "ver=v1"},
+		{path: "/doc/v1/other", wantPattern: "/doc/*", wantParams: "*=v1/other"},
+		{path: "/static/js/app.js", wantPattern: "/static/*", wantParams: "*=js/app.js"},
+		{path: "/nothing"},
+	}
+	testRouterParity(t, parityRoutes, tests)
+}
+
+func TestRouterParityRootWildcard(t *testing.T) {
+	testRouterParity(t, rootWildcardRoutes, []parityCase{
+		{path: "/", wantPattern: "/*", wantParams: "*="},
+		{path: "//", wantPattern: "/*", wantParams: "*="},
+		{path: "/doc", wantPattern: "/*", wantParams: "*=doc"},
+		{path: "/doc/v1", wantPattern: "/doc/*", wantParams: "*=v1"},
+		{path: "/user/42/", wantPattern: "/*", wantParams: "*=user/42"},
+	})
+}
+
+func testRouterParity(t *testing.T, routes []string, tests []parityCase) {
+	hs := newParityHandlers(routes)
+	for _, tt := range tests {
+		for name, h := range hs {
+			t.Run(name+tt.path, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+				route, params := h.(routeFinder).find(r, http.MethodGet, tt.path)
+				if tt.wantPattern == "" {
+					if route != nil {
+						t.Fatalf("matched %s, want no route", route.info.Pattern)
+					}
+					return
+				}
+				if route == nil {
+					t.Fatalf("no route, want %s", tt.wantPattern)
+				}
+				if route.info.Pattern != tt.wantPattern {
+					t.Errorf("pattern = %s, want %s", route.info.Pattern, tt.wantPattern)
+				}
+				if got := formatParams(params); got != tt.wantParams {
+					t.Errorf("params = %s, want %s", got, tt.wantParams)
+				}
+			})
+		}
+	}
+}
+
+func TestRouterParityAllowed(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/user/list", want: "GET, HEAD, OPTIONS, POST"},
+		{path: "/a/b/d", want: "GET, HEAD, OPTIONS"},
+		{path: "/a/b/e", want: ""},
+	}
+	hs := newParityHandlers(parityRoutes)
+	for _, h := range hs {
+		h.Route(http.MethodPost, "/user/list", nopHandler)
+	}
+
+	for _, tt := range tests {
+		for name, h := range hs {
+			t.Run(name+tt.path, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+				got := strings.Join(h.(routeFinder).allowed(r, tt.path), ", ")
+				if got != tt.want {
+					t.Errorf("allowed = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
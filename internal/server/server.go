@@ -3,13 +3,25 @@ package server
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"log"
 	"myserver/internal/ctx"
 	"net/http"
 )
 
 type Server interface {
+	http.Handler
 	Routable
+	// NotFound 设置404的处理函数
+	NotFound(h ctx.HandleFunc)
+	// MethodNotAllowed 设置405的处理函数
+	MethodNotAllowed(h ctx.HandleFunc)
+	// Routes 按注册顺序返回所有路由
+	Routes() []RouteInfo
+	// URLFor 根据路由名字和参数生成路径
+	URLFor(name string, params ...string) (string, error)
+	// SetPathOptions 设置路由前路径规范化的配置
+	SetPathOptions(opts PathOptions)
 	Start(port string) error
 	Shutdown(ctx context.Context) error
 }
@@ -18,16 +30,71 @@ type MyServer struct {
 	handler Handler
 }
 
+// NewServer 默认使用TreeBasedHandler
 func NewServer(middlewares ...ctx.HandleFunc) Server {
+	return NewServerWithHandler(NewTreeBasedHandler(middlewares...))
+}
+
+// NewServerWithHandler 使用指定的Handler实现，全局中间件在创建Handler时传入
+// 比如 NewServerWithHandler(NewRadixTreeHandler(mws...))，或者通过 NewHandler 按配置的名字创建
+func NewServerWithHandler(h Handler) Server {
 	return &MyServer{
-		//handler: NewMapBasedHandler(),
-		handler: NewTreeBasedHandler(middlewares...),
+		handler: h,
 	}
 }
 
-func (s *MyServer) Route(method, path string, hfs ...ctx.HandleFunc) {
-	log.Printf("method:%s, path:%s\n", method, path)
-	s.handler.Route(method, path, hfs...)
+func (s *MyServer) Route(method, path string, hfs ...ctx.HandleFunc) *Route {
+	return s.addRoute(&routeSpec{method: method, path: path, handlers: hfs})
+}
+
+func (s *MyServer) addRoute(spec *routeSpec) *Route {
+	log.Printf("method:%s, path:%s\n", spec.method, spec.path)
+	return addRouteTo(s.handler, spec)
+}
+
+func (s *MyServer) Routes() []RouteInfo {
+	return s.handler.Routes()
+}
+
+func (s *MyServer) URLFor(name string, params ...string) (string, error) {
+	return s.handler.URLFor(name, params...)
+}
+
+func (s *MyServer) Group(prefix string, middlewares ...ctx.HandleFunc) Routable {
+	return newRouteGroup(s, prefix, middlewares)
+}
+
+func (s *MyServer) Host(pattern string) Routable {
+	return newConditionGroup(s, hostCondition(pattern))
+}
+
+func (s *MyServer) Header(key, value string) Routable {
+	return newConditionGroup(s, headerCondition(key, value))
+}
+
+func (s *MyServer) Mount(prefix string, h http.Handler) {
+	mount(s, prefix, h)
+}
+
+func (s *MyServer) Static(prefix string, fsys fs.FS, opts StaticOptions) {
+	static(s, prefix, fsys, opts)
+}
+
+// ServeHTTP 实现 http.Handler，一个server可以挂载到另一个server下
+func (s *MyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *MyServer) NotFound(h ctx.HandleFunc) {
+	s.handler.NotFound(h)
+}
+
+func (s *MyServer) MethodNotAllowed(h ctx.HandleFunc) {
+	s.handler.MethodNotAllowed(h)
+}
+
+func (s *MyServer) SetPathOptions(opts PathOptions) {
+	s.handler.SetPathOptions(opts)
 }
 
 func (s *MyServer) Start(port string) error {
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"myserver/internal/ctx"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// StaticOptions Static 的配置，零值表示使用 index.html 作为索引文件，不列目录，不查找预压缩文件
+type StaticOptions struct {
+	// Index 请求目录时依次尝试的索引文件，为nil时使用 index.html
+	Index []string
+	// Browse 目录下没有索引文件时返回文件列表，否则返回404
+	Browse bool
+	// Precompressed 客户端支持的话，优先返回同名的 .br、.gz 文件
+	Precompressed bool
+}
+
+// precompressedExts 预压缩文件的编码和后缀，按优先级排列
+var precompressedExts = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// static 把fsys挂载到prefix下，只注册GET，HEAD由路由自动处理
+// 依赖通配符路由，MapBasedHandler不支持
+func static(r Routable, prefix string, fsys fs.FS, opts StaticOptions) {
+	if opts.Index == nil {
+		opts.Index = []string{"index.html"}
+	}
+	s := &staticHandler{fsys: fsys, opts: opts}
+	route := r.Route(http.MethodGet, prefix, s.serve)
+	r.Route(http.MethodGet, joinPath(prefix, "*"), s.serve)
+	if route != nil {
+		// 目录没有末尾的 / 时serveDir会重定向到 prefix/，这里不能再重定向回去
+		route.keepSlash = true
+		s.base = route.base
+	}
+}
+
+type staticHandler struct {
+	fsys fs.FS
+	opts StaticOptions
+	// 用来调用NotFound设置的处理函数，外部实现的Routable为nil
+	base *baseHandler
+	// embed.FS 没有修改时间，用内容计算ETag，name -> etag
+	etags sync.Map
+}
+
+func (s *staticHandler) serve(c *ctx.Context) {
+	name, ok := staticName(c.Param("*"))
+	if !ok {
+		s.notFound(c)
+		return
+	}
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		s.notFound(c)
+		return
+	}
+
+	// 和 http.FileServer 一样，目录以 / 结尾，文件不以 / 结尾，使用相对路径重定向
+	p := c.R.URL.Path
+	if !info.IsDir() {
+		if strings.HasSuffix(p, "/") {
+			localRedirect(c, "../"+path.Base(p))
+			return
+		}
+		s.serveFile(c, name, info)
+		return
+	}
+	if !strings.HasSuffix(p, "/") {
+		localRedirect(c, path.Base(p)+"/")
+		return
+	}
+	for _, index := range s.opts.Index {
+		f := path.Join(name, index)
+		if fi, err := fs.Stat(s.fsys, f); err == nil && !fi.IsDir() {
+			s.serveFile(c, f, fi)
+			return
+		}
+	}
+	if s.opts.Browse {
+		s.serveDir(c, name)
+		return
+	}
+	s.notFound(c)
+}
+
+// serveFile Range、If-None-Match、If-Modified-Since 等交给 http.ServeContent 处理
+func (s *staticHandler) serveFile(c *ctx.Context, name string, info fs.FileInfo) {
+	h := c.W.Header()
+	file, encoding := name, ""
+	if s.opts.Precompressed {
+		h.Add("Vary", "Accept-Encoding")
+		accept := c.R.Header.Get("Accept-Encoding")
+		for _, e := range precompressedExts {
+			if !acceptsEncoding(accept, e.encoding) {
+				continue
+			}
+			if fi, err := fs.Stat(s.fsys, name+e.ext); err == nil && !fi.IsDir() {
+				file, encoding, info = name+e.ext, e.encoding, fi
+				break
+			}
+		}
+	}
+
+	f, err := s.fsys.Open(file)
+	if err != nil {
+		s.notFound(c)
+		return
+	}
+	defer f.Close()
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			s.notFound(c)
+			return
+		}
+		rs = bytes.NewReader(data)
+	}
+
+	if encoding != "" {
+		// 压缩后的内容没法探测类型，按原文件的后缀设置
+		ctype := mime.TypeByExtension(path.Ext(name))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		h.Set("Content-Type", ctype)
+		h.Set("Content-Encoding", encoding)
+	}
+	if etag := s.etag(file, info, rs); etag != "" {
+		h.Set("ETag", etag)
+	}
+	http.ServeContent(c.W, c.R, name, info.ModTime(), rs)
+}
+
+// etag 有修改时间的话用修改时间和大小，否则用内容的hash，计算后缓存
+func (s *staticHandler) etag(name string, info fs.FileInfo, rs io.ReadSeeker) string {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+	}
+	if v, ok := s.etags.Load(name); ok {
+		return v.(string)
+	}
+	sum := sha256.New()
+	if _, err := io.Copy(sum, rs); err != nil {
+		return ""
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	etag := fmt.Sprintf(`"%x"`, sum.Sum(nil)[:16])
+	s.etags.Store(name, etag)
+	return etag
+}
+
+func (s *staticHandler) serveDir(c *ctx.Context, name string) {
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		s.notFound(c)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("<!doctype html>\n<meta charset=\"utf-8\">\n<pre>\n")
+	for _, e := range entries {
+		n := e.Name()
+		if e.IsDir() {
+			n += "/"
+		}
+		u := url.URL{Path: n}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", html.EscapeString(u.String()), html.EscapeString(n))
+	}
+	b.WriteString("</pre>\n")
+
+	c.W.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.W.WriteHeader(http.StatusOK)
+	c.W.Write([]byte(b.String()))
+}
+
+func (s *staticHandler) notFound(c *ctx.Context) {
+	if s.base != nil {
+		s.base.notFound(c)
+		return
+	}
+	defaultNotFound(c)
+}
+
+// staticName 把通配符匹配到的路径转成fs.FS使用的名字
+// 包含 .. 、反斜杠或者空字符的路径直接拒绝，防止访问到fsys之外的文件
+func staticName(p string) (string, bool) {
+	if strings.ContainsAny(p, "\\\x00") {
+		return "", false
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return "", false
+		}
+	}
+	name := strings.Trim(path.Clean("/"+p), "/")
+	if name == "" {
+		name = "."
+	}
+	return name, fs.ValidPath(name)
+}
+
+// acceptsEncoding 检查 Accept-Encoding 是否接受encoding，q=0 表示不接受
+func acceptsEncoding(accept, encoding string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(fields[0]), encoding) {
+			continue
+		}
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") && strings.Trim(f[2:], "0.") == "" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// localRedirect 相对路径的重定向，保留query，挂载在任意前缀下都能正确跳转
+func localRedirect(c *ctx.Context, target string) {
+	if q := c.R.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	c.W.Header().Set("Location", target)
+	c.W.WriteHeader(http.StatusMovedPermanently)
+}
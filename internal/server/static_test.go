@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticRedirectTrailingSlash(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("home")},
+		"js/app.js":       {Data: []byte("app")},
+		"docs/index.html": {Data: []byte("docs")},
+	}
+	tests := []struct {
+		path         string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{path: "/static", wantCode: http.StatusMovedPermanently, wantLocation: "static/"},
+		{path: "/static/", wantCode: http.StatusOK, wantBody: "home"},
+		{path: "/static/docs", wantCode: http.StatusMovedPermanently, wantLocation: "docs/"},
+		{path: "/static/docs/", wantCode: http.StatusOK, wantBody: "docs"},
+		{path: "/static/js/app.js", wantCode: http.StatusOK, wantBody: "app"},
+	}
+	for name, newHandler := range map[string]func() Handler{
+		"tree":  func() Handler { return NewTreeBasedHandler() },
+		"radix": func() Handler { return NewRadixTreeHandler() },
+	} {
+		h := newHandler()
+		h.SetPathOptions(PathOptions{CleanPath: true, RedirectTrailingSlash: true})
+		h.Static("/static", fsys, StaticOptions{})
+
+		for _, tt := range tests {
+			t.Run(name+tt.path, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+				if w.Code != tt.wantCode {
+					t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+				}
+				if loc := w.Header().Get("Location"); loc != tt.wantLocation {
+					t.Errorf("Location = %q, want %q", loc, tt.wantLocation)
+				}
+				if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+					t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"myserver/internal/ctx"
+	"myserver/internal/entity/dto"
+	"net/http"
+)
+
+// NotFound 路由不存在时统一返回CommonResponse
+func NotFound(c *ctx.Context) {
+	rsp := &dto.CommonResponse{
+		Code: http.StatusNotFound,
+		Msg:  "not found",
+	}
+	if err := c.Render(http.StatusNotFound, rsp); err != nil {
+		log.Printf("write failed, err:%v\n", err)
+	}
+}
+
+// MethodNotAllowed method不匹配时统一返回CommonResponse，Allow 头由路由设置
+func MethodNotAllowed(c *ctx.Context) {
+	rsp := &dto.CommonResponse{
+		Code: http.StatusMethodNotAllowed,
+		Msg:  "method not allowed",
+	}
+	if err := c.Render(http.StatusMethodNotAllowed, rsp); err != nil {
+		log.Printf("write failed, err:%v\n", err)
+	}
+}
+
+// checkParams 参数转换有错误时统一返回400，返回false表示已经响应过了
+func checkParams(c *ctx.Context) bool {
+	errs := c.ParamErrors()
+	if len(errs) == 0 {
+		return true
+	}
+	rsp := &dto.CommonResponse{
+		Code: http.StatusBadRequest,
+		Msg:  "invalid params",
+		Data: errs,
+	}
+	if err := c.Render(http.StatusBadRequest, rsp); err != nil {
+		log.Printf("write failed, err:%v\n", err)
+	}
+	return false
+}
+
+// bind 绑定并校验请求，失败时统一返回错误，返回false表示已经响应过了
+// 字段错误返回400并在data中列出所有字段，body超过限制返回413，Content-Type不支持时返回415
+func bind(c *ctx.Context, req interface{}) bool {
+	err := c.Bind(req)
+	if err == nil {
+		return true
+	}
+	log.Printf("bind failed, path:%s, err:%v\n", c.R.URL.Path, err)
+
+	code := http.StatusBadRequest
+	rsp := &dto.CommonResponse{
+		Code: code,
+		Msg:  "invalid request",
+	}
+	var fieldErrs ctx.FieldErrors
+	switch {
+	case errors.As(err, &fieldErrs):
+		rsp.Data = fieldErrs
+	case errors.Is(err, ctx.ErrBodyTooLarge), errors.Is(err, ctx.ErrFileTooLarge):
+		code = http.StatusRequestEntityTooLarge
+		rsp.Code, rsp.Msg = code, "request body too large"
+	case errors.Is(err, ctx.ErrUnsupportedMediaType), errors.Is(err, ctx.ErrFileTypeNotAllowed):
+		code = http.StatusUnsupportedMediaType
+		rsp.Code, rsp.Msg = code, "unsupported media type"
+	default:
+		rsp.Msg = err.Error()
+	}
+	if err := c.Render(code, rsp); err != nil {
+		log.Printf("write failed, err:%v\n", err)
+	}
+	return false
+}
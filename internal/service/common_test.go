@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/json"
+	"myserver/internal/ctx"
+	"myserver/internal/entity/dto"
+	"myserver/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{name: "ok", contentType: "application/json", body: `{"name":"tom"}`, wantCode: http.StatusOK},
+		{name: "too large", contentType: "application/json", body: `{"name":"` + strings.Repeat("a", 64) + `"}`,
+			wantCode: http.StatusRequestEntityTooLarge},
+		{name: "bad json", contentType: "application/json", body: `{"name":`, wantCode: http.StatusBadRequest},
+		{name: "unsupported media type", contentType: "text/plain", body: "tom", wantCode: http.StatusUnsupportedMediaType},
+	}
+	h := ctx.ToHandler(middleware.BodyLimit(32), func(c *ctx.Context) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		if bind(c, &req) {
+			c.W.WriteHeader(http.StatusOK)
+		}
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK {
+				return
+			}
+			var rsp dto.CommonResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+				t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+			}
+			if rsp.Code != tt.wantCode {
+				t.Errorf("response code = %d, want %d", rsp.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -24,12 +24,10 @@ func NewKafkaService(k *mq.KafkaCli) *KafkaServiceImpl {
 
 func (k *KafkaServiceImpl) Publish(c *ctx.Context) {
 	req := &dto.KafkaPublishReq{}
-	if err := c.ReadJson(req); err != nil {
-		log.Printf("read json failed, req:%v, err:%v\n", req, err)
-		c.W.WriteHeader(http.StatusBadRequest)
+	if !bind(c, req) {
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c, 5*time.Second)
 	defer cancel()
 	if err := k.kafka.Publish(ctx, req.Topic, req.Msgs); err != nil {
 		log.Printf("publish failed, req:%v, err:%v\n", req, err)
@@ -41,7 +39,7 @@ func (k *KafkaServiceImpl) Publish(c *ctx.Context) {
 		Code: 0,
 		Msg:  "success",
 	}
-	if err := c.WriteJson(http.StatusOK, rsp); err != nil {
+	if err := c.Render(http.StatusOK, rsp); err != nil {
 		log.Printf("write failed, err:%v\n", err)
 	}
 }
@@ -24,13 +24,11 @@ func NewMQService(mq *mq.RabbitMQ) MQService {
 
 func (s *MQServiceImpl) Push(c *ctx.Context) {
 	req := &dto.MQPushReq{}
-	if err := c.ReadJson(req); err != nil {
-		log.Printf("read json failed, req:%v, err:%v\n", req, err)
-		c.W.WriteHeader(http.StatusBadRequest)
+	if !bind(c, req) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c, 5*time.Second)
 	defer cancel()
 	if err := s.mq.Push(ctx, req.ExchangeName, req.RoutingKey, []byte(req.Body)); err != nil {
 		log.Printf("push failed, req:%v, err:%v\n", req, err)
@@ -42,7 +40,7 @@ func (s *MQServiceImpl) Push(c *ctx.Context) {
 		Code: 0,
 		Msg:  "success",
 	}
-	if err := c.WriteJson(http.StatusOK, rsp); err != nil {
+	if err := c.Render(http.StatusOK, rsp); err != nil {
 		log.Printf("write failed, err:%v\n", err)
 	}
 }
@@ -52,13 +50,11 @@ func (s *MQServiceImpl) Consume(c *ctx.Context) {
 
 func (s *MQServiceImpl) CreateExchange(c *ctx.Context) {
 	req := &dto.MQCreateExchangeReq{}
-	if err := c.ReadJson(req); err != nil {
-		log.Printf("read json failed, req:%v, err:%v\n", req, err)
-		c.W.WriteHeader(http.StatusBadRequest)
+	if !bind(c, req) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c, 5*time.Second)
 	defer cancel()
 	if err := s.mq.CreateExchange(ctx, req.ExchangeName, req.ExchangeType); err != nil {
 		log.Printf("create exchange failed, req:%v, err:%v\n", req, err)
@@ -70,19 +66,17 @@ func (s *MQServiceImpl) CreateExchange(c *ctx.Context) {
 		Code: 0,
 		Msg:  "success",
 	}
-	if err := c.WriteJson(http.StatusOK, rsp); err != nil {
+	if err := c.Render(http.StatusOK, rsp); err != nil {
 		log.Printf("write failed, err:%v\n", err)
 	}
 }
 
 func (s *MQServiceImpl) DeclareAndBindQueue(c *ctx.Context) {
 	req := &dto.MQQueueBindReq{}
-	if err := c.ReadJson(req); err != nil {
-		log.Printf("read json failed, req:%v, err:%v\n", req, err)
-		c.W.WriteHeader(http.StatusBadRequest)
+	if !bind(c, req) {
 		return
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(c, 5*time.Second)
 	defer cancel()
 	if err := s.mq.DeclareAndBindQueue(ctx, req.QueueName, req.BindingKey, req.ExchangeName); err != nil {
 		log.Printf("declare and bind queue failed, req:%v, err:%v\n", req, err)
@@ -94,7 +88,7 @@ func (s *MQServiceImpl) DeclareAndBindQueue(c *ctx.Context) {
 		Code: 0,
 		Msg:  "success",
 	}
-	if err := c.WriteJson(http.StatusOK, rsp); err != nil {
+	if err := c.Render(http.StatusOK, rsp); err != nil {
 		log.Printf("write failed, err:%v\n", err)
 	}
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"myserver/internal/ctx"
+	"myserver/internal/middleware"
 	"myserver/internal/server"
 	"net/http"
 )
@@ -11,10 +12,10 @@ type UserService interface {
 	SignUp(c *ctx.Context)
 }
 
-func RegisterUserService(svr server.Server, user UserService) {
-	svr.Route(http.MethodGet, "/user/list", user.List)
+func RegisterUserService(svr server.Routable, user UserService) {
+	svr.Route(http.MethodGet, "/user/list", user.List).Name("user.list")
 	svr.Route(http.MethodGet, "/user/*", user.List)
-	svr.Route(http.MethodPost, "/user/signup", user.SignUp)
+	svr.Route(http.MethodPost, "/user/signup", user.SignUp).Name("user.signup")
 }
 
 type MQService interface {
@@ -24,16 +25,19 @@ type MQService interface {
 	Consume(c *ctx.Context)
 }
 
-func RegisterMQService(svr server.Server, mq MQService) {
-	svr.Route(http.MethodPost, "/mq/push", mq.Push)
-	svr.Route(http.MethodPost, "/mq/exchange/create", mq.CreateExchange)
-	svr.Route(http.MethodPost, "/mq/queue/declare_bind", mq.DeclareAndBindQueue)
+func RegisterMQService(svr server.Routable, mq MQService) {
+	// 消息体不会很大，限制在1M以内
+	g := svr.Group("/mq", middleware.BodyLimit(1<<20))
+	g.Route(http.MethodPost, "/push", mq.Push)
+	g.Route(http.MethodPost, "/exchange/create", mq.CreateExchange)
+	g.Route(http.MethodPost, "/queue/declare_bind", mq.DeclareAndBindQueue)
 }
 
 type KafkaService interface {
 	Publish(c *ctx.Context)
 }
 
-func RegisterKafkaService(svr server.Server, kaf KafkaService) {
-	svr.Route(http.MethodPost, "/mq/kafka/publist", kaf.Publish)
+func RegisterKafkaService(svr server.Routable, kaf KafkaService) {
+	g := svr.Group("/mq/kafka")
+	g.Route(http.MethodPost, "/publist", kaf.Publish)
 }
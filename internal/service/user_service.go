@@ -5,7 +5,6 @@ import (
 	"myserver/internal/ctx"
 	"myserver/internal/entity/dto"
 	"net/http"
-	"strconv"
 	"time"
 )
 
@@ -17,9 +16,7 @@ func DefaultUserService() UserService {
 
 func (u *UserServiceImpl) SignUp(c *ctx.Context) {
 	user := &dto.User{}
-	if err := c.ReadJson(user); err != nil {
-		log.Printf("read json failed, err:%v\n", err)
-		c.W.WriteHeader(http.StatusBadRequest)
+	if !bind(c, user) {
 		return
 	}
 
@@ -29,7 +26,7 @@ func (u *UserServiceImpl) SignUp(c *ctx.Context) {
 		Code: 0,
 		Msg:  "success",
 	}
-	if err := c.WriteJson(http.StatusOK, rsp); err != nil {
+	if err := c.Render(http.StatusOK, rsp); err != nil {
 		log.Printf("write error:%v\n", err)
 	}
 }
@@ -47,15 +44,18 @@ func (u *UserServiceImpl) List(c *ctx.Context) {
 		},
 	}
 
-	q := c.R.URL.Query()
-	delay := q.Get("delay")
-	delayMs := 0
-	if len(delay) > 0 {
-		delayMs, _ = strconv.Atoi(delay)
+	delayMs := c.QueryInt("delay", 0)
+	if !checkParams(c) {
+		return
 	}
 
 	if delayMs > 0 {
-		time.Sleep(time.Duration(delayMs) * time.Millisecond)
+		select {
+		case <-time.After(time.Duration(delayMs) * time.Millisecond):
+		case <-c.Done():
+			log.Printf("list canceled, err:%v\n", c.Err())
+			return
+		}
 	}
 
 	rsp := &dto.CommonResponse{
@@ -63,5 +63,5 @@ func (u *UserServiceImpl) List(c *ctx.Context) {
 		Msg:  "success",
 		Data: users,
 	}
-	c.WriteJson(http.StatusOK, rsp)
+	c.Render(http.StatusOK, rsp)
 }